@@ -1,16 +1,27 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/dsrosen6/hyprlaptop/internal/app"
 	"github.com/dsrosen6/hyprlaptop/internal/config"
 	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+	"github.com/dsrosen6/hyprlaptop/internal/listener"
+	"github.com/dsrosen6/hyprlaptop/internal/state"
 )
 
 const (
@@ -18,16 +29,26 @@ const (
 )
 
 var (
-	a              *app.App
-	saveDiplaysCmd = flag.NewFlagSet("save-displays", flag.ExitOnError)
-	mtrName        = saveDiplaysCmd.String("laptop", "", "name of laptop display")
+	a *app.App
+	// globals are the global flags, for the bare commands that read the config themselves.
+	globals globalOptions
 )
 
 // Run is the primary entry point of hyprlaptop. It is used both to launch the listener
-// and to handle CLI commands.
-func Run() error {
-	ctx := context.Background()
-	if err := parseFlags(); err != nil {
+// and to handle CLI commands, and returns the exit code.
+func Run() int {
+	if len(os.Args) > 1 && os.Args[1] == completeCommand {
+		handleComplete(os.Args[2:])
+		return 0
+	}
+
+	return finish(run())
+}
+
+func run() error {
+	g, args, err := parseGlobalFlags(os.Args[1:])
+	globals, jsonOutput = g, g.json
+	if err != nil {
 		return fmt.Errorf("parsing cli flags: %w", err)
 	}
 
@@ -35,11 +56,48 @@ func Run() error {
 		slog.SetLogLoggerLevel(slog.LevelDebug)
 	}
 
+	if len(args) == 0 {
+		if g.help {
+			return runHelp(rootCommands, nil)
+		}
+
+		// a manual or catchall run
+		if err := initApp(g.cfgFile); err != nil {
+			return err
+		}
+		return handleRefresh()
+	}
+
+	cmd, path, rest, err := resolveCommand(rootCommands, args)
+	if err != nil {
+		return err
+	}
+
+	fs := cmd.flagSet(strings.Join(path, " "))
+	positional, err := parseArgs(fs, rest)
+	if errors.Is(err, flag.ErrHelp) || g.help {
+		return runHelp(rootCommands, path)
+	}
+	if err != nil {
+		return usageErrorf("%w; run 'hyprlaptop help %s'", err, strings.Join(path, " "))
+	}
+
+	if !cmd.bare {
+		if err := initApp(g.cfgFile); err != nil {
+			return err
+		}
+	}
+
+	return cmd.run(positional)
+}
+
+// initApp reads the config and connects to Hyprland.
+func initApp(cfgFile string) error {
 	cfg, err := config.InitConfig(cfgFile)
 	if err != nil {
 		return fmt.Errorf("reading config: %w", err)
 	}
-	slog.Debug("initiated config", "path", cfg.Path)
+	slog.Debug("initiated config", "path", cfg.Path())
 
 	hc, err := hypr.NewHyprctlClient()
 	if err != nil {
@@ -47,40 +105,22 @@ func Run() error {
 	}
 
 	a = app.NewApp(cfg, hc)
-	if err := handleCommands(ctx, os.Args[1:]); err != nil {
-		return err
-	}
-
+	a.ConfigNamed = cfgFile != ""
 	return nil
 }
 
-// handleCommands parses and handles CLI options.
-func handleCommands(ctx context.Context, args []string) error {
-	if len(args) == 0 {
-		return handleRefresh()
-	}
-
-	switch args[0] {
-	case "version":
+func handleVersion() error {
+	return printResult(versionResult{Version: version}, func() error {
 		fmt.Println(version)
 		return nil
-	case "save-displays", "sd":
-		return handleSaveDisplays(args)
-	case "lid", "lid-switch":
-		return handleLidSwitch()
-	case "wake":
-		return handleWake()
-	case "listen":
-		return handleListen(ctx)
-	default:
-		return errors.New("invalid command")
-	}
+	})
 }
 
 // handleRefresh runs a regular refresh of hyprlaptop if no subcommands
-// are passed. It is a manual or catchall run.
+// are passed. It is a manual or catchall run. Like all other commands, it is run
+// by the listener if it is running so the two never apply changes at the same time.
 func handleRefresh() error {
-	if err := a.Run(); err != nil {
+	if _, err := a.Dispatch(listener.Request{Command: listener.RefreshEvent}); err != nil {
 		return fmt.Errorf("refreshing: %w", err)
 	}
 
@@ -90,39 +130,164 @@ func handleRefresh() error {
 // handleSaveDisplays saves the current arrangement of displays into the config,
 // essentially freezing the setup state for future runs. This is a way around
 // manually inputting your config.
+//
+// The laptop display can be passed either with -laptop or as the only argument; if
+// neither is given, the configured one is used if connected, or else the first eDP
+// display. Only the connected displays are updated unless -replace is passed.
 func handleSaveDisplays(args []string) error {
-	expectedArgs := 1
-	gotArgs := len(args) - 1
-	if gotArgs != expectedArgs {
-		return fmt.Errorf("expected %d arguments, got %d", expectedArgs, gotArgs)
+	if len(args) > 1 {
+		return usageErrorf("expected at most 1 argument (laptop display), got %d", len(args))
+	}
+
+	if saveFlags.confirm && jsonOutput {
+		return usageErrorf("-confirm can't be used with --json")
 	}
 
-	if err := saveDiplaysCmd.Parse(args[1:]); err != nil {
-		return fmt.Errorf("parsing arguments: %w", err)
+	opts := app.SaveDisplaysOptions{
+		Laptop:  saveFlags.laptop,
+		Profile: saveFlags.profile,
+		Replace: saveFlags.replace,
+		Exclude: saveFlags.exclude,
 	}
 
-	if err := a.SaveCurrentDisplays(*mtrName); err != nil {
-		return fmt.Errorf("setting laptop display: %w", err)
+	if opts.Laptop == "" && len(args) > 0 {
+		opts.Laptop = args[0]
 	}
 
-	fmt.Printf("Laptop display '%s' saved to config.\n", a.Cfg.LaptopDisplay.Name)
-	externals := a.Cfg.ExternalDisplays
-	switch len(externals) {
+	if saveFlags.confirm {
+		opts.DryRun = true
+		res, err := saveDisplays(opts)
+		if err != nil {
+			return err
+		}
+
+		if len(res.Changes) == 0 {
+			printKeptDisplays(res.Kept)
+			fmt.Println("No changes to save.")
+			return nil
+		}
+
+		printDisplayChanges(res.Changes)
+		printKeptDisplays(res.Kept)
+		if !confirm("Write these changes to the config?") {
+			fmt.Println("Nothing written.")
+			return nil
+		}
+		opts.DryRun = false
+	}
+
+	res, err := saveDisplays(opts)
+	if err != nil {
+		return err
+	}
+
+	return printResult(res, func() error { return printSaveResult(res) })
+}
+
+func printSaveResult(res app.SaveDisplaysResult) error {
+	if !saveFlags.confirm {
+		printDisplayChanges(res.Changes)
+		printKeptDisplays(res.Kept)
+	}
+
+	if !res.Written {
+		fmt.Println("No changes to save.")
+		return nil
+	}
+
+	if res.Profile != "" {
+		fmt.Printf("Profile '%s' saved to config.\n", res.Profile)
+		return nil
+	}
+
+	fmt.Printf("Laptop display '%s' saved to config.\n", res.Laptop)
+	switch len(res.Externals) {
 	case 0:
-		fmt.Println("No external display detected.")
+		fmt.Println("No external displays saved.")
 	default:
 		fmt.Println("Saved external display(s):")
-		for _, e := range externals {
-			fmt.Printf("	%s\n", e.Name)
+		for _, e := range res.Externals {
+			fmt.Printf("	%s\n", e)
 		}
 	}
 
 	return nil
 }
 
+func saveDisplays(opts app.SaveDisplaysOptions) (app.SaveDisplaysResult, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return app.SaveDisplaysResult{}, fmt.Errorf("marshaling save-displays options: %w", err)
+	}
+
+	data, err := a.Dispatch(listener.Request{Command: listener.SaveDisplaysEvent, Args: []string{string(b)}})
+	if err != nil {
+		return app.SaveDisplaysResult{}, fmt.Errorf("saving displays: %w", err)
+	}
+
+	var res app.SaveDisplaysResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return app.SaveDisplaysResult{}, fmt.Errorf("reading save-displays result: %w", err)
+	}
+
+	return res, nil
+}
+
+// printKeptDisplays notes the displays -replace couldn't remove because they come from
+// an included file.
+func printKeptDisplays(kept []string) {
+	for _, name := range kept {
+		fmt.Printf("= %s: kept, it is saved in an included file; remove it there\n", name)
+	}
+}
+
+// printDisplayChanges prints the changes save-displays makes, in a diff-like format.
+func printDisplayChanges(changes []app.DisplayChange) {
+	for _, c := range changes {
+		name := c.Name
+		if c.Laptop {
+			name += " (laptop)"
+		}
+
+		switch c.Kind {
+		case app.ChangeAdd:
+			fmt.Printf("+ %s: %s\n", name, formatLayout(*c.After))
+		case app.ChangeRemove:
+			fmt.Printf("- %s: %s\n", name, formatLayout(*c.Before))
+		case app.ChangeUpdate:
+			fmt.Printf("~ %s\n", name)
+			for _, f := range c.Fields {
+				fmt.Printf("    %s: %s -> %s\n", f.Field, f.From, f.To)
+			}
+		}
+	}
+}
+
+func formatLayout(m hypr.Monitor) string {
+	return fmt.Sprintf("%dx%d@%g at %dx%d, scale %g", m.Width, m.Height, m.RefreshRate, m.X, m.Y, m.Scale)
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 // handleLidSwitch handles the lid switch; meant to be wired up to binds in hyprland.
-func handleLidSwitch() error {
-	if err := app.SendLidCommand(); err != nil {
+// The bind can optionally pass the new state ("open" or "closed").
+func handleLidSwitch(args []string) error {
+	if len(args) > 1 {
+		return usageErrorf("expected at most 1 argument (open or closed), got %d", len(args))
+	}
+
+	req := listener.Request{Command: listener.LidSwitchEvent, Args: args}
+	if _, err := a.Dispatch(req); err != nil {
 		return fmt.Errorf("sending lid switch command: %w", err)
 	}
 
@@ -133,19 +298,442 @@ func handleLidSwitch() error {
 // This handles situations where, perhaps, you shut your laptop (suspending it) and then
 // it is plugged into a dock. Otherwise, it would wake with the laptop display on while it is shut.
 func handleWake() error {
-	if err := app.SendWakeCommand(); err != nil {
+	if _, err := a.Dispatch(listener.Request{Command: listener.IdleWakeEvent}); err != nil {
 		return fmt.Errorf("sending wake command: %w", err)
 	}
 
 	return nil
 }
 
+// handleHistory lists the layouts hyprlaptop has applied, newest first.
+func handleHistory() error {
+	h, err := state.LoadHistory()
+	if err != nil {
+		return fmt.Errorf("loading layout history: %w", err)
+	}
+
+	if h == nil {
+		h = state.History{}
+	}
+
+	return printResult(h, func() error { return printHistory(h) })
+}
+
+func printHistory(h state.History) error {
+	if len(h) == 0 {
+		fmt.Println("No layouts in history.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, e := range h {
+		var monitors []string
+		for _, m := range e.Monitors {
+			if m.Disabled {
+				monitors = append(monitors, fmt.Sprintf("%s (disabled)", m.Name))
+				continue
+			}
+			monitors = append(monitors, fmt.Sprintf("%s %dx%d+%d+%d@%gx", m.Name, m.Width, m.Height, m.X, m.Y, m.Scale))
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i, e.Time.Local().Format(time.DateTime), e.Trigger, e.Status,
+			strings.Join(monitors, ", "))
+	}
+
+	return tw.Flush()
+}
+
+// handleRestore reapplies a layout from the history; "undo" restores the previous one.
+func handleRestore(args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("expected 1 argument (history index), got %d", len(args))
+	}
+
+	req := listener.Request{Command: listener.RestoreLayoutEvent, Args: args}
+	if _, err := a.Dispatch(req); err != nil {
+		return fmt.Errorf("restoring layout: %w", err)
+	}
+
+	return nil
+}
+
+// handleMode sets a manual display mode that takes precedence over the lid and hotplug
+// status until the connected displays change, or until "mode auto" is run.
+func handleMode(args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("expected 1 argument (%s), got %d", strings.Join(app.ModeNames(), ", "), len(args))
+	}
+
+	data, err := a.Dispatch(listener.Request{Command: listener.SetModeEvent, Args: args})
+	if err != nil {
+		return fmt.Errorf("setting mode: %w", err)
+	}
+
+	return printMode(data)
+}
+
+// handleCycle switches to the next display mode; meant to be bound to a key, like Win+P.
+func handleCycle() error {
+	data, err := a.Dispatch(listener.Request{Command: listener.CycleModeEvent})
+	if err != nil {
+		return fmt.Errorf("cycling mode: %w", err)
+	}
+
+	return printMode(data)
+}
+
+// handleProfile applies a profile from the config until the connected displays change.
+func handleProfile(args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("expected 1 argument (profile name), got %d", len(args))
+	}
+
+	data, err := a.Dispatch(listener.Request{Command: listener.SetProfileEvent, Args: args})
+	if err != nil {
+		return fmt.Errorf("setting profile: %w", err)
+	}
+
+	return printMode(data)
+}
+
+// handleMenu lists the modes and profiles that fit the connected displays, either printing
+// them (-list) or letting the user pick one with a dmenu-compatible launcher (-dmenu).
+func handleMenu(args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("expected no arguments, got %d", len(args))
+	}
+
+	if menuFlags.dmenu == "" && !menuFlags.list {
+		return usageErrorf("one of -dmenu or -list is required")
+	}
+
+	entries, err := a.MenuEntries()
+	if err != nil {
+		return fmt.Errorf("getting menu entries: %w", err)
+	}
+
+	var labels []string
+	for _, e := range entries {
+		labels = append(labels, e.Label)
+	}
+
+	if menuFlags.list {
+		if entries == nil {
+			entries = []app.MenuEntry{}
+		}
+		return printResult(entries, func() error {
+			fmt.Println(strings.Join(labels, "\n"))
+			return nil
+		})
+	}
+
+	c := exec.Command("sh", "-c", menuFlags.dmenu)
+	c.Stdin = strings.NewReader(strings.Join(labels, "\n") + "\n")
+	c.Stderr = os.Stderr
+	out, err := c.Output()
+	if err != nil {
+		// launchers exit non-zero when the menu is dismissed
+		slog.Debug("launcher exited without a selection", "error", err)
+		return nil
+	}
+
+	choice := strings.TrimSpace(string(out))
+	i := slices.IndexFunc(entries, func(e app.MenuEntry) bool { return e.Label == choice })
+	if i == -1 {
+		return fmt.Errorf("unknown menu entry '%s'", choice)
+	}
+
+	switch e := entries[i]; e.Kind {
+	case "profile":
+		return handleProfile([]string{e.Value})
+	default:
+		return handleMode([]string{e.Value})
+	}
+}
+
+// handleMonitors lists the connected monitors, enabled or not, with the config entry and
+// profiles each one matches and how its live layout differs from them.
+func handleMonitors(args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("expected no arguments, got %d", len(args))
+	}
+
+	infos, err := a.Monitors()
+	if err != nil {
+		return fmt.Errorf("getting monitors: %w", err)
+	}
+
+	return printResult(infos, func() error {
+		printMonitors(infos)
+		return nil
+	})
+}
+
+func printMonitors(infos []app.MonitorInfo) {
+	if len(infos) == 0 {
+		fmt.Println("No monitors connected.")
+		return
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		m := info.Monitor
+		name := m.Name
+		if info.Laptop {
+			name += " (laptop)"
+		}
+		fmt.Println(name)
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "  Description:\t%s\n", m.Description)
+		if m.Disabled {
+			fmt.Fprintf(tw, "  State:\tdisabled\n")
+		} else {
+			fmt.Fprintf(tw, "  State:\t%s\n", formatLayout(m))
+		}
+		if src := m.Layout().MirrorOf; src != "" {
+			fmt.Fprintf(tw, "  Mirroring:\t%s\n", src)
+		}
+		fmt.Fprintf(tw, "  Modes:\t%s\n", strings.Join(m.AvailableModes, " "))
+		if info.ConfigEntry == "" {
+			fmt.Fprintf(tw, "  Config:\tnot in the config\n")
+		} else {
+			fmt.Fprintf(tw, "  Config:\t%s (%s)\n", info.ConfigEntry, formatMatch(info.Diff))
+		}
+		for _, p := range info.Profiles {
+			fmt.Fprintf(tw, "  Profile:\t%s (%s)\n", p.Name, formatMatch(p.Diff))
+		}
+		_ = tw.Flush()
+
+		printFieldDiffs(info.ConfigEntry, info.Diff)
+		for _, p := range info.Profiles {
+			printFieldDiffs("profiles."+p.Name, p.Diff)
+		}
+	}
+}
+
+func formatMatch(diffs []hypr.FieldDiff) string {
+	if len(diffs) == 0 {
+		return "matches"
+	}
+
+	return fmt.Sprintf("%d field(s) differ", len(diffs))
+}
+
+// printFieldDiffs prints how a monitor's live layout differs from a configured one.
+func printFieldDiffs(entry string, diffs []hypr.FieldDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	fmt.Printf("  Live -> %s:\n", entry)
+	for _, d := range diffs {
+		fmt.Printf("    %s: %s -> %s\n", d.Field, d.From, d.To)
+	}
+}
+
+// handleDoctor checks hyprlaptop's setup, printing a fix for each check that fails.
+func handleDoctor(args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("expected no arguments, got %d", len(args))
+	}
+
+	checks := app.Doctor(globals.cfgFile)
+	if err := printResult(checks, func() error { return printChecks(checks) }); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if !c.OK {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return reportedError{fmt.Errorf("%d of %d checks failed", failed, len(checks))}
+	}
+
+	return nil
+}
+
+func printChecks(checks []app.Check) error {
+	failed := 0
+	for _, c := range checks {
+		result := "ok"
+		if !c.OK {
+			result = "FAIL"
+			failed++
+		}
+
+		line := fmt.Sprintf("[%-4s] %s", result, c.Name)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		fmt.Println(line)
+
+		if !c.OK && c.Fix != "" {
+			fmt.Printf("       fix: %s\n", c.Fix)
+		}
+	}
+
+	fmt.Println()
+	if failed == 0 {
+		fmt.Println("All checks passed.")
+	} else {
+		fmt.Printf("%d of %d checks failed.\n", failed, len(checks))
+	}
+
+	return nil
+}
+
+// handleStatus prints the state of the listener and whether the config is valid.
+func handleStatus() error {
+	data, err := a.Dispatch(listener.Request{Command: listener.StatusEvent})
+	if err != nil {
+		return fmt.Errorf("getting status: %w", err)
+	}
+
+	var res app.StatusResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("reading status: %w", err)
+	}
+
+	if err := printResult(res, func() error { return printStatus(res) }); err != nil {
+		return err
+	}
+
+	// the exit code tells scripts whether all is well; the text output already says why not
+	switch {
+	case !res.Listener:
+		return reportedError{app.ErrDaemonNotRunning}
+	case res.ConfigError != "":
+		return reportedError{fmt.Errorf("%w: %s", config.ErrInvalid, res.ConfigError)}
+	default:
+		return nil
+	}
+}
+
+func printStatus(res app.StatusResult) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if res.Listener {
+		fmt.Fprintf(tw, "Listener:\trunning (pid %d, since %s)\n", res.PID, res.StartedAt.Local().Format(time.DateTime))
+	} else {
+		fmt.Fprintf(tw, "Listener:\tnot running\n")
+	}
+
+	fmt.Fprintf(tw, "Config:\t%s\n", res.ConfigPath)
+	if res.ConfigHost != "" {
+		fmt.Fprintf(tw, "Host section:\t%s\n", res.ConfigHost)
+	}
+
+	switch {
+	case res.ConfigError == "":
+		fmt.Fprintf(tw, "Config status:\tvalid\n")
+	case res.Listener && !res.ConfigErrorAt.IsZero():
+		fmt.Fprintf(tw, "Config status:\tinvalid since %s; using the last good config\n",
+			res.ConfigErrorAt.Local().Format(time.DateTime))
+	default:
+		fmt.Fprintf(tw, "Config status:\tinvalid\n")
+	}
+
+	if res.Listener {
+		mode := res.Mode
+		if res.Profile != "" {
+			mode = fmt.Sprintf("%s (%s)", mode, res.Profile)
+		}
+		fmt.Fprintf(tw, "Status:\t%s\n", res.Status)
+		fmt.Fprintf(tw, "Lid:\t%s\n", res.LidState)
+		fmt.Fprintf(tw, "Mode:\t%s\n", mode)
+		fmt.Fprintf(tw, "Enabled:\t%s\n", strings.Join(res.Enabled, ", "))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if res.ConfigError != "" {
+		fmt.Printf("\nConfig errors:\n%s\n", res.ConfigError)
+	}
+
+	return nil
+}
+
+// handleConfigConvert converts the config file to another format. The old file is kept
+// with a .bak suffix.
+func handleConfigConvert(args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("expected no arguments, got %d", len(args))
+	}
+
+	if convertFlags.to == "" {
+		return usageErrorf("-to is required (json, toml or yaml)")
+	}
+
+	f, err := config.ParseFormat(convertFlags.to)
+	if err != nil {
+		return usageError{err}
+	}
+
+	old := a.Cfg.Path()
+	path, err := a.Cfg.Convert(f)
+	if err != nil {
+		return fmt.Errorf("converting config: %w", err)
+	}
+
+	res := convertResult{Format: string(f), Path: path, Backup: old + ".bak"}
+	return printResult(res, func() error {
+		fmt.Printf("Config converted to %s: %s\n", res.Format, res.Path)
+		fmt.Printf("The old config was moved to %s. Restart the listener to pick up the new file.\n", res.Backup)
+		return nil
+	})
+}
+
+// handleConfigDump prints the config as merged from the main file and any included files.
+func handleConfigDump() error {
+	host, _ := a.Cfg.Host()
+	res := dumpResult{Sources: a.Cfg.Sources(), HostSection: host, Config: a.Cfg}
+	return printResult(res, func() error {
+		b, err := a.Cfg.Dump()
+		if err != nil {
+			return fmt.Errorf("dumping config: %w", err)
+		}
+
+		_, err = os.Stdout.Write(b)
+		return err
+	})
+}
+
+func printMode(data json.RawMessage) error {
+	var res app.ModeResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("reading mode result: %w", err)
+	}
+
+	return printResult(res, func() error {
+		if res.Profile != "" {
+			fmt.Printf("Profile: %s\n", res.Profile)
+			return nil
+		}
+
+		fmt.Printf("Mode: %s\n", res.Mode)
+		return nil
+	})
+}
+
 // handleListen is the entry point to the listener; meant to be run as a systemd user unit
 // or as an exec-once in hyprland, depending on if you're using UWSM.
-func handleListen(ctx context.Context) error {
+func handleListen() error {
+	// stop cleanly on SIGTERM (e.g. from systemd) so the persisted state reflects it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	slog.Info("initializing socket connection")
 	slog.Info("listening for hyprland events")
-	if err := a.Listen(ctx); err != nil {
+	if err := a.Listen(ctx); err != nil && !errors.Is(err, context.Canceled) {
 		return err
 	}
 
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// command is a hyprlaptop subcommand.
+type command struct {
+	name    string
+	aliases []string
+	// args is the synopsis of the positional arguments, e.g. "[open|closed]".
+	args    string
+	summary string
+	// help is a longer description, shown in the command's help.
+	help string
+	// bare commands run without reading the config or connecting to Hyprland.
+	bare bool
+
+	// flags registers the command's flags on its flag set.
+	flags func(fs *flag.FlagSet)
+	// flagValues complete the values of the command's flags, by flag name.
+	flagValues map[string]func() []string
+	// complete returns the candidates for the command's positional arguments, given the
+	// ones already typed.
+	complete func(args []string) []string
+
+	subcommands []*command
+	run         func(args []string) error
+}
+
+// globalOptions are the flags accepted in any position, before or after the command.
+type globalOptions struct {
+	cfgFile string
+	help    bool
+	json    bool
+}
+
+// parseGlobalFlags pulls the global flags out of args wherever they are, up to a "--",
+// and returns the options and the rest of the arguments.
+func parseGlobalFlags(args []string) (globalOptions, []string, error) {
+	var g globalOptions
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") {
+			rest = append(rest, a)
+			continue
+		}
+
+		switch name {
+		case "c", "config":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return g, nil, usageErrorf("flag needs an argument: %s", a)
+				}
+				i++
+				value = args[i]
+			}
+			g.cfgFile = value
+		case "h", "help":
+			g.help = true
+		case "json":
+			g.json = true
+		default:
+			rest = append(rest, a)
+		}
+	}
+
+	return g, rest, nil
+}
+
+// findCommand looks up a command by name or alias.
+func findCommand(cmds []*command, name string) (*command, bool) {
+	for _, c := range cmds {
+		if c.name == name || slices.Contains(c.aliases, name) {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// lookupCommand finds the command named by args, descending into subcommands, and
+// returns it with the names used to reach it and the remaining arguments. The command
+// is nil if args don't start with one.
+func lookupCommand(cmds []*command, args []string) (*command, []string, []string) {
+	var cmd *command
+	var path []string
+	for len(args) > 0 {
+		c, ok := findCommand(cmds, args[0])
+		if !ok {
+			break
+		}
+
+		cmd, cmds = c, c.subcommands
+		path = append(path, c.name)
+		args = args[1:]
+	}
+
+	return cmd, path, args
+}
+
+// resolveCommand is lookupCommand for running a command: it fails if there is no
+// runnable command.
+func resolveCommand(cmds []*command, args []string) (*command, []string, []string, error) {
+	cmd, path, rest := lookupCommand(cmds, args)
+	if cmd == nil {
+		return nil, nil, nil, usageErrorf("unknown command '%s'; run 'hyprlaptop help' for a list of commands", args[0])
+	}
+
+	if cmd.run == nil {
+		full := strings.Join(path, " ")
+		if len(rest) == 0 {
+			return nil, nil, nil, usageErrorf("'hyprlaptop %s' needs a subcommand; run 'hyprlaptop help %s'", full, full)
+		}
+		return nil, nil, nil, usageErrorf("unknown command 'hyprlaptop %s %s'; run 'hyprlaptop help %s'", full, rest[0], full)
+	}
+
+	return cmd, path, rest, nil
+}
+
+// flagSet returns a new flag set with the command's flags registered on it.
+func (c *command) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	if c.flags != nil {
+		c.flags(fs)
+	}
+
+	return fs
+}
+
+// parseArgs parses flags anywhere among the arguments, not just before the first
+// positional one, and returns the positional arguments. Everything after "--" is
+// positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var tail []string
+	if i := slices.Index(args, "--"); i != -1 {
+		args, tail = args[:i], args[i+1:]
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, tail...), nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// takesValue reports whether a flag needs a value, i.e. isn't a boolean flag.
+func takesValue(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+func printUsage(w io.Writer, cmds []*command) {
+	fmt.Fprintln(w, "Usage: hyprlaptop [-c <config>] [--json] [command] [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "With no command, hyprlaptop checks the current displays and applies any changes needed.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	printCommandList(w, cmds, "")
+	fmt.Fprintln(w)
+	printGlobalFlags(w)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'hyprlaptop help <command>' for more about a command.")
+}
+
+func printCommandList(w io.Writer, cmds []*command, prefix string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, c := range cmds {
+		name := prefix + c.name
+		if c.args != "" {
+			name += " " + c.args
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, c.summary)
+	}
+	_ = tw.Flush()
+}
+
+func printGlobalFlags(w io.Writer) {
+	fmt.Fprintln(w, "Global flags (accepted anywhere):")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "  -c, --config <file>\tconfig file to use instead of ~/.config/hypr/hyprlaptop.json\n")
+	fmt.Fprintf(tw, "  -h, --help\tshow help\n")
+	fmt.Fprintf(tw, "  --json\tprint the result as JSON\n")
+	_ = tw.Flush()
+}
+
+// printCommandHelp prints the help for a command; path is the names leading to it.
+func printCommandHelp(w io.Writer, c *command, path []string) {
+	full := strings.Join(path, " ")
+	fs := c.flagSet(full)
+
+	usage := "hyprlaptop " + full
+	switch {
+	case len(c.subcommands) > 0 && c.run == nil:
+		usage += " <command>"
+	default:
+		if hasFlags(fs) {
+			usage += " [flags]"
+		}
+		if c.args != "" {
+			usage += " " + c.args
+		}
+	}
+
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", usage, c.summary)
+	if c.help != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(c.help))
+	}
+
+	if len(c.aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(c.aliases, ", "))
+	}
+
+	if len(c.subcommands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		printCommandList(w, c.subcommands, full+" ")
+	}
+
+	if hasFlags(fs) {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+
+	fmt.Fprintln(w)
+	printGlobalFlags(w)
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+// runHelp prints the help for the command named by args, or the general usage.
+func runHelp(cmds []*command, args []string) error {
+	if len(args) == 0 {
+		return printHelp(func(w io.Writer) { printUsage(w, cmds) })
+	}
+
+	c, path, rest := lookupCommand(cmds, args)
+	if c == nil || len(rest) > 0 {
+		return usageErrorf("no help for unknown command '%s'", strings.Join(args, " "))
+	}
+
+	return printHelp(func(w io.Writer) { printCommandHelp(w, c, path) })
+}
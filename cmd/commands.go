@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/dsrosen6/hyprlaptop/internal/app"
+	"github.com/dsrosen6/hyprlaptop/internal/state"
+)
+
+// rootCommands are every hyprlaptop command, in the order they are listed in the help.
+var rootCommands []*command
+
+func init() {
+	rootCommands = commands()
+}
+
+func commands() []*command {
+	return []*command{
+		{
+			name:    "listen",
+			summary: "Run the listener",
+			help: `Runs the listener, which applies changes when displays are plugged in or unplugged,
+the lid is opened or closed, or the config changes. Other commands are sent to it
+over its command socket while it is running. Meant to be run as a systemd user
+unit, or with exec-once in hyprland.conf.`,
+			run: func([]string) error { return handleListen() },
+		},
+		{
+			name:    "lid",
+			aliases: []string{"lid-switch"},
+			args:    "[open|closed]",
+			summary: "Notify the listener of a lid switch (for bindl)",
+			help: `Meant to be wired up to the lid switch binds in hyprland.conf. The new state is
+optional; the lid state is read from ACPI or logind first.`,
+			complete: firstArg(list("open", "closed")),
+			run:      handleLidSwitch,
+		},
+		{
+			name:    "wake",
+			summary: "Notify the listener of a wake from sleep (for hypridle)",
+			help:    `Meant to be put into after_sleep_cmd in hypridle.conf.`,
+			run:     func([]string) error { return handleWake() },
+		},
+		{
+			name:    "save-displays",
+			aliases: []string{"sd"},
+			args:    "[laptop]",
+			summary: "Save the current display arrangement to the config",
+			help: `Saves the layout of the connected displays into the config. Only the displays
+connected right now are added or updated unless -replace is given. The laptop
+display can be given as the argument or with -laptop; if neither is given, the
+configured laptop display is used if it is connected, or else the first eDP display.
+The laptop display can't be excluded.`,
+			flags: registerSaveFlags,
+			flagValues: map[string]func() []string{
+				"laptop":  monitorNames,
+				"profile": profileNames,
+				"exclude": monitorNames,
+			},
+			complete: firstArg(monitorNames),
+			run:      handleSaveDisplays,
+		},
+		{
+			name:    "status",
+			summary: "Show the listener's state and whether the config is valid",
+			run:     func([]string) error { return handleStatus() },
+		},
+		{
+			name:    "monitors",
+			summary: "List the connected monitors and how they match the config",
+			help: `Lists every connected monitor, including disabled ones, with its description and
+modes, whether it is the laptop display, and the config entry and profiles it is in.
+Where the live layout differs from a configured one, the fields that differ are shown.`,
+			run: handleMonitors,
+		},
+		{
+			name:    "doctor",
+			summary: "Check the setup and show how to fix what's missing",
+			help: `Checks everything hyprlaptop needs: the Hyprland environment and sockets, a lid
+state source, the config, the listener, the lid binds in hyprland.conf, the wake
+command in hypridle.conf, and that the laptop display is connected. Each failed
+check comes with a fix. Exits with 1 if any check fails.`,
+			bare: true,
+			run:  handleDoctor,
+		},
+		{
+			name:    "history",
+			summary: "List the last layouts hyprlaptop applied, newest first",
+			bare:    true,
+			run:     func([]string) error { return handleHistory() },
+		},
+		{
+			name:    "undo",
+			summary: "Reapply the layout before the current one",
+			run:     func([]string) error { return handleRestore([]string{"1"}) },
+		},
+		{
+			name:     "restore",
+			args:     "<n>",
+			summary:  "Reapply layout n from the history",
+			complete: firstArg(historyIndices),
+			run:      handleRestore,
+		},
+		{
+			name:    "mode",
+			args:    "<mode>",
+			summary: "Set the display mode",
+			help: `Sets a manual display mode that takes precedence over the lid and hotplug status
+until a display is plugged in or unplugged:
+
+  extend    the laptop display and externals are all enabled
+  mirror    externals mirror the laptop display
+  external  only the externals are enabled
+  laptop    only the laptop display is enabled
+  present   like extend, but displays not in the config mirror the laptop display
+  auto      clear the override`,
+			complete: firstArg(app.ModeNames),
+			run:      handleMode,
+		},
+		{
+			name:    "cycle",
+			summary: "Switch to the next display mode",
+			run:     func([]string) error { return handleCycle() },
+		},
+		{
+			name:     "profile",
+			args:     "<name>",
+			summary:  "Apply a profile from the config",
+			complete: firstArg(profileNames),
+			run:      handleProfile,
+		},
+		{
+			name:    "menu",
+			summary: "Pick a mode or profile with a launcher, or list them",
+			help: `Lists the modes and the profiles whose displays are all connected. With -dmenu,
+they are shown in a dmenu-compatible launcher (e.g. "fuzzel --dmenu") and the one
+picked is applied; with -list, they are just printed.`,
+			flags: registerMenuFlags,
+			run:   handleMenu,
+		},
+		{
+			name:    "config",
+			summary: "Convert or inspect the config",
+			subcommands: []*command{
+				{
+					name:       "convert",
+					summary:    "Convert the config to json, toml or yaml",
+					flags:      registerConvertFlags,
+					flagValues: map[string]func() []string{"to": list("json", "toml", "yaml")},
+					run:        handleConfigConvert,
+				},
+				{
+					name:    "dump",
+					summary: "Print the config merged from all of its files",
+					run:     func([]string) error { return handleConfigDump() },
+				},
+			},
+		},
+		{
+			name:     "completion",
+			args:     "<bash|zsh|fish>",
+			summary:  "Print a shell completion script",
+			help:     completionHelp,
+			bare:     true,
+			complete: firstArg(list("bash", "zsh", "fish")),
+			run:      handleCompletion,
+		},
+		{
+			name:    "version",
+			summary: "Print the version",
+			bare:    true,
+			run:     func([]string) error { return handleVersion() },
+		},
+		{
+			name:     "help",
+			args:     "[command]",
+			summary:  "Show help for a command",
+			bare:     true,
+			complete: func(args []string) []string { return commandNames(rootCommands, args) },
+			run:      func(args []string) error { return runHelp(rootCommands, args) },
+		},
+	}
+}
+
+// firstArg completes the first positional argument from values, and nothing after it.
+func firstArg(values func() []string) func(args []string) []string {
+	return func(args []string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+
+		return values()
+	}
+}
+
+// list returns a fixed list of completions.
+func list(values ...string) func() []string {
+	return func() []string { return values }
+}
+
+// commandNames completes command names, descending into subcommands.
+func commandNames(cmds []*command, args []string) []string {
+	for _, a := range args {
+		c, ok := findCommand(cmds, a)
+		if !ok {
+			return nil
+		}
+		cmds = c.subcommands
+	}
+
+	var names []string
+	for _, c := range cmds {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+// monitorNames returns the names of the connected monitors, for completions.
+func monitorNames() []string {
+	if a == nil || a.Hctl == nil {
+		return nil
+	}
+
+	ms, err := a.Hctl.ListMonitors()
+	if err != nil {
+		return nil
+	}
+
+	return slices.Sorted(maps.Keys(ms))
+}
+
+// profileNames returns the names of the profiles in the config, for completions.
+func profileNames() []string {
+	if a == nil || a.Cfg == nil {
+		return nil
+	}
+
+	return slices.Sorted(maps.Keys(a.Cfg.Profiles))
+}
+
+// historyIndices returns the indices of the layouts in the history, for completions.
+func historyIndices() []string {
+	h, err := state.LoadHistory()
+	if err != nil {
+		return nil
+	}
+
+	var idx []string
+	for i := range h {
+		idx = append(idx, strconv.Itoa(i))
+	}
+
+	return idx
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/dsrosen6/hyprlaptop/internal/app"
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+// completeCommand is the hidden command the completion scripts call with the words typed
+// so far, the last being the one to complete. It prints a candidate per line; if there
+// are none, the shells fall back to completing file names.
+const completeCommand = "__complete"
+
+const completionHelp = `Prints a completion script for bash, zsh or fish. Monitor and profile names are
+completed from the connected monitors and the config. For example:
+
+  bash:  echo 'source <(hyprlaptop completion bash)' >> ~/.bashrc
+  zsh:   hyprlaptop completion zsh > "${fpath[1]}/_hyprlaptop"
+  fish:  hyprlaptop completion fish > ~/.config/fish/completions/hyprlaptop.fish`
+
+const bashCompletion = `# bash completion for hyprlaptop
+_hyprlaptop() {
+    local IFS=$'\n'
+    COMPREPLY=($(hyprlaptop __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+
+complete -o default -F _hyprlaptop hyprlaptop
+`
+
+const zshCompletion = `#compdef hyprlaptop
+# zsh completion for hyprlaptop
+
+_hyprlaptop() {
+    local -a candidates
+    candidates=("${(@f)$(hyprlaptop __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+
+if [[ $funcstack[1] == _hyprlaptop ]]; then
+    _hyprlaptop "$@"
+else
+    compdef _hyprlaptop hyprlaptop
+fi
+`
+
+const fishCompletion = `# fish completion for hyprlaptop
+function __hyprlaptop_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    hyprlaptop __complete $tokens "$current" 2>/dev/null
+end
+
+complete -c hyprlaptop -s c -l config -r -F -d 'config file to use'
+complete -c hyprlaptop -f -a '(__hyprlaptop_complete)'
+`
+
+// handleCompletion prints the completion script for a shell.
+func handleCompletion(args []string) error {
+	if len(args) != 1 {
+		return usageErrorf("expected 1 argument (bash, zsh or fish), got %d", len(args))
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return usageErrorf("unsupported shell '%s' (expected bash, zsh or fish)", args[0])
+	}
+
+	return printResult(completionResult{Shell: args[0], Script: script}, func() error {
+		fmt.Print(script)
+		return nil
+	})
+}
+
+// handleComplete prints the completion candidates for the last of words. Errors are
+// never printed, since the output goes straight into the shell's completions.
+func handleComplete(words []string) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+	for _, c := range completions(cur, prev) {
+		if strings.HasPrefix(c, cur) {
+			fmt.Println(c)
+		}
+	}
+}
+
+func completions(cur string, prev []string) []string {
+	g, rest, err := parseGlobalFlags(prev)
+	if err != nil {
+		// the value of -c, which is a file name
+		return nil
+	}
+
+	// completions work without a config or Hyprland, just without live names
+	initCompletionApp(g.cfgFile)
+
+	cmd, path, args := lookupCommand(rootCommands, rest)
+	if cmd == nil {
+		if len(rest) > 0 {
+			return nil
+		}
+		if strings.HasPrefix(cur, "-") {
+			return []string{"--config", "--help", "--json"}
+		}
+		return commandNames(rootCommands, nil)
+	}
+
+	if cmd.run == nil {
+		if len(args) > 0 {
+			return nil
+		}
+		return commandNames(cmd.subcommands, nil)
+	}
+
+	fs := cmd.flagSet(strings.Join(path, " "))
+
+	// the value of one of the command's flags
+	if name, _, ok := strings.Cut(strings.TrimLeft(cur, "-"), "="); ok && strings.HasPrefix(cur, "-") {
+		var cs []string
+		if values, ok := cmd.flagValues[name]; ok {
+			for _, v := range values() {
+				cs = append(cs, fmt.Sprintf("-%s=%s", name, v))
+			}
+		}
+		return cs
+	}
+
+	if len(args) > 0 {
+		if name, ok := flagName(args[len(args)-1]); ok {
+			if f := fs.Lookup(name); f != nil && takesValue(f) {
+				if values, ok := cmd.flagValues[name]; ok {
+					return values()
+				}
+				return nil
+			}
+		}
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		var cs []string
+		fs.VisitAll(func(f *flag.Flag) { cs = append(cs, "-"+f.Name) })
+		return append(cs, "--config", "--help", "--json")
+	}
+
+	if cmd.complete == nil {
+		return nil
+	}
+
+	return cmd.complete(positionalArgs(fs, args))
+}
+
+// initCompletionApp is initApp for completions. Unlike initApp, it never creates a
+// config file, since the path may only be partly typed; what can't be read is left nil.
+func initCompletionApp(cfgFile string) {
+	var cfg *config.Config
+	if path, err := config.ResolvePath(cfgFile); err == nil {
+		cfg, _ = config.Read(path)
+	}
+
+	hc, _ := hypr.NewHyprctlClient()
+	a = app.NewApp(cfg, hc)
+}
+
+// flagName returns the name of a flag argument like "-laptop", or false if the argument
+// isn't a flag or already has its value ("-laptop=eDP-1").
+func flagName(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" || strings.Contains(arg, "=") {
+		return "", false
+	}
+
+	return strings.TrimLeft(arg, "-"), true
+}
+
+// positionalArgs returns the positional arguments among args, skipping flags and their
+// values, without failing on unknown flags like parseArgs does.
+func positionalArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		name, ok := flagName(args[i])
+		if !ok {
+			if !strings.HasPrefix(args[i], "-") {
+				positional = append(positional, args[i])
+			}
+			continue
+		}
+
+		if f := fs.Lookup(name); f != nil && takesValue(f) {
+			i++
+		}
+	}
+
+	return positional
+}
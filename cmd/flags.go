@@ -2,29 +2,46 @@ package cmd
 
 import (
 	"flag"
-	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 )
 
-const (
-	defaultDir  = "hypr"
-	defaultFile = "hyprlaptop.json"
-)
+// Flag values for the commands that take flags; they are registered on each command's
+// flag set in commands.go.
+var (
+	saveFlags struct {
+		laptop  string
+		profile string
+		replace bool
+		confirm bool
+		exclude []string
+	}
 
-var cfgFile string
+	menuFlags struct {
+		dmenu string
+		list  bool
+	}
 
-// parseFlags parses command line flags (currently just config path override)
-func parseFlags() error {
-	flag.StringVar(&cfgFile, "c", "", "specify a config file")
-	flag.Parse()
-	if cfgFile == "" {
-		cd, err := os.UserConfigDir()
-		if err != nil {
-			return fmt.Errorf("getting user config directory: %w", err)
-		}
-		cfgFile = filepath.Join(cd, defaultDir, defaultFile)
+	convertFlags struct {
+		to string
 	}
+)
+
+func registerSaveFlags(fs *flag.FlagSet) {
+	fs.StringVar(&saveFlags.laptop, "laptop", "", "`name` of laptop display")
+	fs.StringVar(&saveFlags.profile, "profile", "", "save into the `profile` with this name instead")
+	fs.BoolVar(&saveFlags.replace, "replace", false, "replace all saved displays instead of merging")
+	fs.BoolVar(&saveFlags.confirm, "confirm", false, "show the changes and confirm before writing")
+	fs.Func("exclude", "`display` to leave out (repeatable or comma-separated)", func(s string) error {
+		saveFlags.exclude = append(saveFlags.exclude, strings.Split(s, ",")...)
+		return nil
+	})
+}
+
+func registerMenuFlags(fs *flag.FlagSet) {
+	fs.StringVar(&menuFlags.dmenu, "dmenu", "", "dmenu-compatible launcher `command` to pick a layout with")
+	fs.BoolVar(&menuFlags.list, "list", false, "print the available layouts instead of showing a menu")
+}
 
-	return nil
+func registerConvertFlags(fs *flag.FlagSet) {
+	fs.StringVar(&convertFlags.to, "to", "", "`format` to convert the config to: json, toml or yaml")
 }
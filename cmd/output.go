@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsrosen6/hyprlaptop/internal/app"
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+)
+
+// Exit codes, so scripts can tell failures apart without parsing the error.
+const (
+	exitError            = 1
+	exitUsage            = 2
+	exitDaemonNotRunning = 3
+	exitInvalidConfig    = 4
+	exitHyprland         = 5
+)
+
+// codeUsage is the error code for a bad command line; the rest come from app.ErrorCode.
+const codeUsage = "usage"
+
+var exitCodes = map[string]int{
+	app.CodeError:            exitError,
+	codeUsage:                exitUsage,
+	app.CodeDaemonNotRunning: exitDaemonNotRunning,
+	app.CodeInvalidConfig:    exitInvalidConfig,
+	app.CodeHyprland:         exitHyprland,
+}
+
+// With --json, a command's result is kept here instead of being printed, and printed in
+// the JSON envelope when the command finishes.
+var (
+	jsonOutput bool
+	jsonData   any
+)
+
+// envelope is what every command prints with --json. Data is command-specific and null
+// for commands with nothing to report; Error is only set when OK is false.
+type envelope struct {
+	OK    bool           `json:"ok"`
+	Data  any            `json:"data"`
+	Error *envelopeError `json:"error,omitempty"`
+}
+
+type envelopeError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// The data of commands whose results aren't already types in the app package.
+type (
+	versionResult struct {
+		Version string `json:"version"`
+	}
+
+	convertResult struct {
+		Format string `json:"format"`
+		Path   string `json:"path"`
+		Backup string `json:"backup"`
+	}
+
+	dumpResult struct {
+		Sources     []string       `json:"sources"`
+		HostSection string         `json:"host_section,omitempty"`
+		Config      *config.Config `json:"config"`
+	}
+
+	completionResult struct {
+		Shell  string `json:"shell"`
+		Script string `json:"script"`
+	}
+)
+
+// usageError is a bad command line: an unknown command or flag, or the wrong arguments.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// reportedError is an error whose message the command has already shown in its text
+// output, e.g. status reporting that the listener isn't running. It still sets the exit
+// code, and is still included with --json.
+type reportedError struct {
+	err error
+}
+
+func (e reportedError) Error() string {
+	return e.err.Error()
+}
+
+func (e reportedError) Unwrap() error {
+	return e.err
+}
+
+// printResult prints a command's result with text, or with --json keeps it for the envelope.
+func printResult(data any, text func() error) error {
+	if jsonOutput {
+		jsonData = data
+		return nil
+	}
+
+	return text()
+}
+
+// printHelp prints help text, which with --json is the "help" field of the data.
+func printHelp(print func(w io.Writer)) error {
+	var b bytes.Buffer
+	print(&b)
+	return printResult(struct {
+		Help string `json:"help"`
+	}{b.String()}, func() error {
+		_, err := os.Stdout.Write(b.Bytes())
+		return err
+	})
+}
+
+// errorCode returns the code for an error, as reported with --json.
+func errorCode(err error) string {
+	var ue usageError
+	if errors.As(err, &ue) {
+		return codeUsage
+	}
+
+	return app.ErrorCode(err)
+}
+
+// finish prints the outcome of a command, as the JSON envelope with --json, and returns
+// the exit code.
+func finish(err error) int {
+	code := 0
+	if err != nil {
+		code = exitCodes[errorCode(err)]
+	}
+
+	if !jsonOutput {
+		var re reportedError
+		if err != nil && !errors.As(err, &re) {
+			fmt.Println("Error:", err)
+		}
+		return code
+	}
+
+	env := envelope{OK: err == nil, Data: jsonData}
+	if err != nil {
+		env.Error = &envelopeError{Code: errorCode(err), Message: err.Error()}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(env); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitError
+	}
+
+	return code
+}
@@ -2,6 +2,10 @@ module github.com/dsrosen6/hyprlaptop
 
 go 1.24.9
 
-require github.com/fsnotify/fsnotify v1.9.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/fsnotify/fsnotify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require golang.org/x/sys v0.13.0 // indirect
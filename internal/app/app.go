@@ -1,62 +1,60 @@
 package app
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/dsrosen6/hyprlaptop/internal/config"
 	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+	"github.com/dsrosen6/hyprlaptop/internal/listener"
+	"github.com/dsrosen6/hyprlaptop/internal/state"
 )
 
 type App struct {
 	Hctl *hypr.HyprctlClient
 	Cfg  *config.Config
-}
+	// ConfigNamed is set when the config was picked with -c, so requests to the listener
+	// say which config they are for.
+	ConfigNamed bool
 
-func NewApp(cfg *config.Config, hc *hypr.HyprctlClient) *App {
-	return &App{
-		Hctl: hc,
-		Cfg:  cfg,
-	}
-}
+	// bindLidState is the lid state last reported by the lid command, cleared on wake,
+	// and lastLidState is the last state successfully read from any provider.
+	bindLidState lidState
+	lastLidState lidState
 
-func (a *App) SaveCurrentDisplays(laptop string) error {
-	displays, err := a.Hctl.ListMonitors()
-	if err != nil {
-		return fmt.Errorf("listing displays via hyprctl: %w", err)
-	}
+	selfChanges *changeTracker
 
-	var lm *hypr.Monitor
-	if laptop == "" {
-		for _, m := range displays {
-			if strings.Contains(m.Name, "eDP") {
-				lm = &m
-			}
-		}
-	} else {
-		l, ok := displays[laptop]
-		if ok {
-			lm = &l
-		}
-	}
+	// placements are the saved workspace placements per monitor identity, and lastEnabled
+	// is the set of monitor identities enabled after the last run.
+	placements  state.Placements
+	lastEnabled map[string]bool
 
-	if lm == nil {
-		return fmt.Errorf("display '%s' not found", laptop)
-	}
+	// daemon is the persisted listener state; it is only set when running as the listener.
+	daemon *state.Daemon
 
-	externals := map[string]hypr.Monitor{}
-	for _, m := range displays {
-		if m.Name != lm.Name {
-			externals[m.Name] = m
-		}
-	}
+	// override is the manual display mode set by the user, if any.
+	override *modeOverride
 
-	a.Cfg.LaptopDisplay = *lm
-	a.Cfg.ExternalDisplays = externals
+	// asked is the set of unknown monitor identities the user has been asked about, and
+	// unknownChoices what they chose for each. Both are forgotten once the monitor is
+	// unplugged.
+	asked          map[string]bool
+	unknownChoices map[string]unknownChoice
 
-	if err := a.Cfg.Write(); err != nil {
-		return fmt.Errorf("writing config: %w", err)
-	}
+	// events is the listener's event channel, and done is closed when the listener stops;
+	// they are only set when running as the listener.
+	events chan<- listener.Event
+	done   <-chan struct{}
+
+	learn learnTracker
+
+	// cfgErr is why the config was last rejected, if it is currently invalid.
+	cfgErr *configError
+}
 
-	return nil
+func NewApp(cfg *config.Config, hc *hypr.HyprctlClient) *App {
+	return &App{
+		Hctl:           hc,
+		Cfg:            cfg,
+		selfChanges:    newChangeTracker(),
+		asked:          map[string]bool{},
+		unknownChoices: map[string]unknownChoice{},
+	}
 }
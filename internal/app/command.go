@@ -1,39 +1,134 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
-	"os"
 	"path/filepath"
 
 	"github.com/dsrosen6/hyprlaptop/internal/listener"
 )
 
-func SendLidCommand() error {
-	return sendCommand(string(listener.LidSwitchEvent))
-}
+var ErrDaemonNotRunning = errors.New("command listener not running")
 
-func SendWakeCommand() error {
-	return sendCommand(string(listener.IdleWakeEvent))
+// listenerOnlyCommands set a mode or profile override, which only the listener keeps, so
+// they fail rather than run locally when it isn't running.
+var listenerOnlyCommands = map[listener.EventType]bool{
+	listener.SetModeEvent:    true,
+	listener.CycleModeEvent:  true,
+	listener.SetProfileEvent: true,
 }
 
-func sendCommand(msg string) error {
-	sock := filepath.Join(os.TempDir(), listener.CommandSockName)
-
-	conn, err := net.Dial("unix", sock)
+// SendRequest sends a request to the running listener and waits for its response.
+// It returns ErrDaemonNotRunning if nothing is listening on the command socket.
+func SendRequest(req listener.Request) (json.RawMessage, error) {
+	conn, err := net.Dial("unix", listener.CommandSockPath())
 	if err != nil {
-		return fmt.Errorf("command listener not running")
+		return nil, ErrDaemonNotRunning
 	}
 
 	defer func() {
 		if err := conn.Close(); err != nil {
-			fmt.Printf("Error closing socket connection: %v\n", err)
+			slog.Warn("closing socket connection", "error", err)
 		}
 	}()
 
-	if _, err = conn.Write([]byte(msg)); err != nil {
-		return fmt.Errorf("writing message '%s' to socket: %w", msg, err)
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("writing request '%s' to socket: %w", req.Command, err)
+	}
+
+	var resp listener.Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading response from socket: %w", err)
+	}
+
+	if !resp.OK {
+		return nil, &listenerError{code: resp.Code, msg: resp.Error}
+	}
+
+	return resp.Data, nil
+}
+
+// Dispatch sends a request to the listener if it is running. If no listener answers, the
+// request is handled in this process instead, holding the apply lock so that two CLI
+// invocations can't apply changes at the same time. Mode and profile commands are the
+// exception: they return ErrDaemonNotRunning, since their override would be lost as soon
+// as this process exits.
+func (a *App) Dispatch(req listener.Request) (json.RawMessage, error) {
+	if a.ConfigNamed {
+		p, err := filepath.Abs(a.Cfg.Path())
+		if err != nil {
+			return nil, fmt.Errorf("resolving config path: %w", err)
+		}
+		req.ConfigPath = p
+	}
+
+	data, err := SendRequest(req)
+	if !errors.Is(err, ErrDaemonNotRunning) {
+		return data, err
+	}
+
+	if listenerOnlyCommands[req.Command] {
+		return nil, fmt.Errorf("modes and profiles are kept by the listener: %w", err)
+	}
+
+	slog.Debug("listener not running; handling command locally", "command", req.Command)
+	res, err := a.handleEvent(listener.Event{
+		Type: req.Command,
+		Args: req.Args,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalResult(res)
+}
+
+// checkRequestConfig returns an error if a request from the CLI is for a config other
+// than the one the listener is using.
+func (a *App) checkRequestConfig(ev listener.Event) error {
+	if ev.ConfigPath == "" {
+		return nil
+	}
+
+	own, err := filepath.Abs(a.Cfg.Path())
+	if err != nil {
+		return fmt.Errorf("resolving config path: %w", err)
+	}
+
+	if own != ev.ConfigPath {
+		return fmt.Errorf("the listener uses the config at %s, not %s; restart it with that config, or stop it to run this command on its own",
+			own, ev.ConfigPath)
 	}
 
 	return nil
 }
+
+func marshalResult(res any) (json.RawMessage, error) {
+	if res == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling command result: %w", err)
+	}
+
+	return b, nil
+}
+
+// newResponse builds the listener response for the result of handling a request.
+func newResponse(res any, err error) listener.Response {
+	if err != nil {
+		return listener.Response{Error: err.Error(), Code: ErrorCode(err)}
+	}
+
+	data, err := marshalResult(res)
+	if err != nil {
+		return listener.Response{Error: err.Error()}
+	}
+
+	return listener.Response{OK: true, Data: data}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+	"github.com/dsrosen6/hyprlaptop/internal/state"
+)
+
+// startDaemonState loads the state persisted by the previous listener, logs how this
+// one came to start, and carries over what is still valid for this boot. The state is
+// saved straight away, so it marks this listener as running and keeps what was carried
+// over even if the first run fails.
+func (a *App) startDaemonState() {
+	prev, err := state.LoadDaemon()
+	if err != nil {
+		slog.Warn("loading previous listener state; starting fresh", "error", err)
+	}
+
+	bootID := state.CurrentBootID()
+	a.daemon = &state.Daemon{
+		PID:       os.Getpid(),
+		BootID:    bootID,
+		StartedAt: time.Now(),
+		Running:   true,
+	}
+	defer a.persistDaemonState()
+
+	switch {
+	case prev == nil:
+		slog.Info("fresh start: no previous listener state")
+		return
+	case prev.BootID != bootID:
+		slog.Info("fresh start: previous listener state is from an earlier boot")
+		return
+	case prev.Running:
+		slog.Warn("resumed after crash: previous listener did not shut down cleanly", "pid", prev.PID)
+	default:
+		slog.Info("restarted: previous listener shut down cleanly")
+	}
+
+	// kept until the first run replaces them
+	a.daemon.Status, a.daemon.LidState, a.daemon.Applied = prev.Status, prev.LidState, prev.Applied
+	a.daemon.Connected, a.daemon.Enabled = prev.Connected, prev.Enabled
+	a.daemon.Mode, a.daemon.Profile, a.daemon.ModeConnected = prev.Mode, prev.Profile, prev.ModeConnected
+
+	a.lastLidState = lidState(prev.LidState)
+	if prev.Mode != "" {
+		// cleared on the first run if the connected monitors have changed since
+		a.override = &modeOverride{mode: displayMode(prev.Mode), profile: prev.Profile, connected: prev.ModeConnected}
+	}
+	a.lastEnabled = map[string]bool{}
+	for _, id := range prev.Enabled {
+		a.lastEnabled[id] = true
+	}
+
+	current, err := a.Hctl.ListMonitors()
+	if err != nil {
+		slog.Warn("listing monitors to compare with previous listener state", "error", err)
+		return
+	}
+
+	if connected := connectedIdentities(current); !slices.Equal(connected, prev.Connected) {
+		slog.Info("displays changed while listener was not running", "before", prev.Connected, "now", connected)
+	}
+}
+
+// saveDaemonState persists the result of a run, if running as the listener.
+func (a *App) saveDaemonState(o *getOutputResult, status config.Status, payloads []displayPayload) {
+	if a.daemon == nil {
+		return
+	}
+
+	a.daemon.Enabled = nil
+	a.daemon.Applied = nil
+	for _, p := range payloads {
+		m := p.out
+		m.Disabled = !p.enable
+		a.daemon.Applied = append(a.daemon.Applied, m)
+		if p.enable {
+			a.daemon.Enabled = append(a.daemon.Enabled, p.in.Identity())
+		}
+	}
+	slices.Sort(a.daemon.Enabled)
+
+	a.saveRunState(o, status)
+}
+
+// saveRunState persists what is known after any run, including one that failed to apply
+// its payloads, so the mode override and lid state survive a restart. On its own, what
+// was enabled and applied is left as of the last successful run.
+func (a *App) saveRunState(o *getOutputResult, status config.Status) {
+	if a.daemon == nil {
+		return
+	}
+
+	a.daemon.Status = string(status)
+	a.daemon.LidState = string(o.lidState)
+	a.daemon.Connected = connectedIdentities(o.displays)
+
+	a.daemon.Mode, a.daemon.Profile, a.daemon.ModeConnected = "", "", nil
+	if a.override != nil {
+		a.daemon.Mode = string(a.override.mode)
+		a.daemon.Profile = a.override.profile
+		a.daemon.ModeConnected = a.override.connected
+	}
+
+	a.persistDaemonState()
+}
+
+// stopDaemonState marks the listener as cleanly shut down.
+func (a *App) stopDaemonState() {
+	if a.daemon == nil {
+		return
+	}
+
+	a.daemon.Running = false
+	a.persistDaemonState()
+}
+
+func (a *App) persistDaemonState() {
+	if err := a.daemon.Save(); err != nil {
+		slog.Warn("saving listener state", "error", err)
+	}
+}
+
+func connectedIdentities(mm hypr.MonitorMap) []string {
+	ids := make([]string, 0, len(mm))
+	for _, m := range mm {
+		ids = append(ids, m.Identity())
+	}
+	slices.Sort(ids)
+
+	return ids
+}
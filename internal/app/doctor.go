@@ -0,0 +1,310 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+	"github.com/dsrosen6/hyprlaptop/internal/listener"
+)
+
+// Check is the outcome of one of the doctor command's setup checks. Fix says how to
+// fix a failed check.
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+func pass(name, detail string) Check {
+	return Check{Name: name, OK: true, Detail: detail}
+}
+
+func fail(name, detail, fix string) Check {
+	return Check{Name: name, Detail: detail, Fix: fix}
+}
+
+// lidBinds is what was found of the lid switch binds in hyprland.conf.
+type lidBinds struct {
+	on, off, toggle bool
+	// withState is true if the binds pass the new state to "hyprlaptop lid".
+	withState bool
+	// path is the hyprland.conf the binds were looked for in.
+	path string
+}
+
+func (b lidBinds) complete() bool {
+	return b.toggle || (b.on && b.off)
+}
+
+// Doctor checks hyprlaptop's setup: Hyprland, the lid state sources, the config, the
+// listener, and the lines hyprlaptop needs in hyprland.conf and hypridle.conf. It
+// doesn't need a working config or Hyprland connection, since those are what it checks.
+func Doctor(cfgFile string) []Check {
+	checks := []Check{checkHyprlandEnv()}
+	checks = append(checks, checkHyprlandSockets()...)
+
+	binds, bindsErr := findLidBinds()
+	checks = append(checks, checkLidState(binds))
+
+	cfg, cfgChecks := checkConfig(cfgFile)
+	checks = append(checks, cfgChecks...)
+
+	checks = append(checks,
+		checkListener(),
+		checkLidBinds(binds, bindsErr),
+		checkHypridle(),
+		checkLaptopDisplay(cfg),
+	)
+
+	return checks
+}
+
+func checkHyprlandEnv() Check {
+	const name = "hyprland environment"
+	if missing := hypr.MissingEnvs(); len(missing) > 0 {
+		return fail(name, fmt.Sprintf("%s not set", strings.Join(missing, ", ")),
+			"run hyprlaptop inside a Hyprland session; for the systemd unit, use UWSM or import the "+
+				"environment with 'systemctl --user import-environment HYPRLAND_INSTANCE_SIGNATURE'")
+	}
+
+	return pass(name, "HYPRLAND_INSTANCE_SIGNATURE="+os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"))
+}
+
+func checkHyprlandSockets() []Check {
+	request, event, err := hypr.SocketPaths()
+	if err != nil {
+		return []Check{
+			fail("hyprland request socket", "can't be found without the environment variables", "fix the Hyprland environment first"),
+			fail("hyprland event socket", "can't be found without the environment variables", "fix the Hyprland environment first"),
+		}
+	}
+
+	var checks []Check
+	for _, s := range []struct{ name, path string }{
+		{"hyprland request socket", request},
+		{"hyprland event socket", event},
+	} {
+		conn, err := net.Dial("unix", s.path)
+		if err != nil {
+			checks = append(checks, fail(s.name, fmt.Sprintf("can't connect to %s: %v", s.path, err),
+				"make sure Hyprland is running; a stale HYPRLAND_INSTANCE_SIGNATURE (e.g. from a previous "+
+					"session) points at sockets that are gone"))
+			continue
+		}
+
+		_ = conn.Close()
+		checks = append(checks, pass(s.name, s.path))
+	}
+
+	return checks
+}
+
+func checkLidState(binds lidBinds) Check {
+	const name = "lid state"
+	for _, p := range []struct {
+		name  string
+		state func() (lidState, error)
+	}{{"acpi", acpiLidState}, {"logind", logindLidState}} {
+		ls, err := p.state()
+		if err == nil && ls != lidStateUnknown {
+			return pass(name, fmt.Sprintf("%s (from %s)", ls, p.name))
+		}
+	}
+
+	if binds.withState {
+		return pass(name, "only from the state passed by the lid binds")
+	}
+
+	return fail(name, "neither ACPI nor logind report the lid state",
+		"pass the state in the lid binds: 'hyprlaptop lid open' and 'hyprlaptop lid closed'")
+}
+
+// checkConfig checks that the config file exists, can be read, and is valid. The config
+// is returned if it could be read.
+func checkConfig(cfgFile string) (*config.Config, []Check) {
+	path, err := config.ResolvePath(cfgFile)
+	if err != nil {
+		return nil, []Check{fail("config file", err.Error(), "set $HOME or pass the config with -c")}
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, []Check{
+			fail("config file", fmt.Sprintf("%s: %v", path, err),
+				"run 'hyprlaptop save-displays' to create it from the connected displays"),
+			fail("config valid", "no config to check", "create the config first"),
+		}
+	}
+
+	cfg, err := config.Read(path)
+	if err != nil {
+		return nil, []Check{
+			pass("config file", path),
+			fail("config valid", err.Error(), "fix the file; 'hyprlaptop config dump' shows what it merges"),
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, []Check{
+			pass("config file", path),
+			fail("config valid", strings.ReplaceAll(err.Error(), "\n", "; "), "fix the values listed"),
+		}
+	}
+
+	return cfg, []Check{pass("config file", path), pass("config valid", "")}
+}
+
+func checkListener() Check {
+	const name = "listener"
+	data, err := SendRequest(listener.Request{Command: listener.StatusEvent})
+	if errors.Is(err, ErrDaemonNotRunning) {
+		return fail(name, fmt.Sprintf("nothing listening on %s", listener.CommandSockPath()),
+			"start it with 'systemctl --user enable --now hyprlaptop.service' when using UWSM, or add "+
+				"'exec-once = hyprlaptop listen' to hyprland.conf")
+	}
+	if err != nil {
+		return fail(name, err.Error(), "restart it; 'journalctl --user -u hyprlaptop' shows its logs when it runs under systemd")
+	}
+
+	var res StatusResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fail(name, fmt.Sprintf("reading status: %v", err), "restart it, it may be an older version")
+	}
+
+	return pass(name, fmt.Sprintf("running (pid %d)", res.PID))
+}
+
+// findLidBinds looks for the binds in hyprland.conf (and the files it sources) that call
+// "hyprlaptop lid" on the lid switch.
+func findLidBinds() (lidBinds, error) {
+	path, err := hyprlandConfPath()
+	if err != nil {
+		return lidBinds{}, err
+	}
+
+	lines, err := readHyprConf(path)
+	if err != nil {
+		return lidBinds{}, err
+	}
+
+	b := lidBinds{path: path}
+	for _, l := range lines {
+		// bindl, bindel and so on: binds with the "locked" flag, which fire on switches
+		flags, ok := strings.CutPrefix(l.key, "bind")
+		if !ok || !strings.Contains(flags, "l") || !strings.Contains(l.value, "switch:") {
+			continue
+		}
+
+		args, ok := lidCommandArgs(l.value)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case strings.Contains(l.value, "switch:on:"):
+			b.on = true
+		case strings.Contains(l.value, "switch:off:"):
+			b.off = true
+		default:
+			b.toggle = true
+		}
+
+		if args != "" {
+			b.withState = true
+		}
+	}
+
+	return b, nil
+}
+
+// lidCommandArgs returns the arguments of the "hyprlaptop lid" command in a bind, if it
+// has one.
+func lidCommandArgs(bind string) (string, bool) {
+	for _, cmd := range []string{"hyprlaptop lid-switch", "hyprlaptop lid"} {
+		if _, args, ok := strings.Cut(bind, cmd); ok {
+			return strings.TrimSpace(args), true
+		}
+	}
+
+	return "", false
+}
+
+func checkLidBinds(b lidBinds, err error) Check {
+	const name = "lid binds"
+	const fix = "add 'bindl = , switch:off:Lid Switch, exec, hyprlaptop lid open' and " +
+		"'bindl = , switch:on:Lid Switch, exec, hyprlaptop lid closed' to hyprland.conf"
+	switch {
+	case err != nil:
+		// errors name the file that couldn't be read
+		return fail(name, err.Error(), fix)
+	case b.complete():
+		return pass(name, fmt.Sprintf("found in %s", b.path))
+	case b.on || b.off:
+		return fail(name, fmt.Sprintf("only one of the lid open and closed binds is set in %s", b.path), fix)
+	default:
+		return fail(name, fmt.Sprintf("no bindl lines calling 'hyprlaptop lid' in %s", b.path), fix)
+	}
+}
+
+func checkHypridle() Check {
+	const name = "hypridle wake"
+	const fix = "add 'after_sleep_cmd = hyprlaptop wake' to the general section of hypridle.conf"
+	dir, err := hyprConfDir()
+	if err != nil {
+		return fail(name, err.Error(), fix)
+	}
+
+	path := filepath.Join(dir, "hypridle.conf")
+	lines, err := readHyprConf(path)
+	if err != nil {
+		return fail(name, err.Error(), fix)
+	}
+
+	for _, l := range lines {
+		if l.key == "after_sleep_cmd" && strings.Contains(l.value, "hyprlaptop wake") {
+			return pass(name, fmt.Sprintf("found in %s", path))
+		}
+	}
+
+	return fail(name, fmt.Sprintf("after_sleep_cmd in %s doesn't call 'hyprlaptop wake'", path), fix)
+}
+
+func checkLaptopDisplay(cfg *config.Config) Check {
+	const name = "laptop display"
+	if cfg == nil {
+		return fail(name, "no config to check", "fix the config first")
+	}
+
+	laptop := cfg.LaptopDisplay.Name
+	if laptop == "" {
+		return fail(name, "laptop_display.name is not set",
+			"run 'hyprlaptop save-displays' to save the connected displays with the eDP one as the laptop display")
+	}
+
+	hc, err := hypr.NewHyprctlClient()
+	if err != nil {
+		return fail(name, err.Error(), "install Hyprland's hyprctl, or add it to PATH")
+	}
+
+	ms, err := hc.ListMonitors()
+	if err != nil {
+		return fail(name, fmt.Sprintf("listing monitors: %v", err), "make sure Hyprland is running")
+	}
+
+	if _, ok := ms[laptop]; !ok {
+		names := slices.Sorted(maps.Keys(ms))
+		return fail(name, fmt.Sprintf("'%s' isn't connected (connected: %s)", laptop, strings.Join(names, ", ")),
+			"set laptop_display.name to the laptop's own display, usually an eDP one; 'hyprlaptop monitors' lists them")
+	}
+
+	return pass(name, laptop)
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+// Error codes classify why a command failed, so scripts can tell failures apart. They
+// are sent back over the command socket, so errors from the listener keep theirs.
+const (
+	CodeError            = "error"
+	CodeDaemonNotRunning = "daemon_not_running"
+	CodeInvalidConfig    = "invalid_config"
+	CodeHyprland         = "hyprland"
+)
+
+// listenerError is an error returned by the listener, with the code it classified it as.
+type listenerError struct {
+	code string
+	msg  string
+}
+
+func (e *listenerError) Error() string {
+	return e.msg
+}
+
+// ErrorCode returns the code for an error.
+func ErrorCode(err error) string {
+	var le *listenerError
+	if errors.As(err, &le) && le.code != "" {
+		return le.code
+	}
+
+	var ce *hypr.CommandError
+	switch {
+	case errors.Is(err, ErrDaemonNotRunning):
+		return CodeDaemonNotRunning
+	case errors.Is(err, config.ErrInvalid):
+		return CodeInvalidConfig
+	case errors.As(err, &ce), errors.Is(err, hypr.ErrMissingEnvs):
+		return CodeHyprland
+	default:
+		return CodeError
+	}
+}
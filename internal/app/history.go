@@ -0,0 +1,80 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/state"
+)
+
+// recordHistory adds an applied layout to the layout history.
+func (a *App) recordHistory(s config.Status, payloads []displayPayload, trigger string) {
+	h, err := state.LoadHistory()
+	if err != nil {
+		slog.Warn("loading layout history; starting a new one", "error", err)
+	}
+
+	e := state.HistoryEntry{
+		Time:    time.Now(),
+		Trigger: trigger,
+		Status:  string(s),
+	}
+
+	for _, p := range payloads {
+		m := p.out
+		m.Description = p.in.Description
+		m.Disabled = !p.enable
+		e.Monitors = append(e.Monitors, m)
+	}
+
+	if err := h.Add(e).Save(); err != nil {
+		slog.Warn("saving layout history", "error", err)
+	}
+}
+
+// RestoreHistory reapplies the layout at index n of the history, where 0 is the layout
+// applied most recently. Displays in the layout that aren't connected are skipped, and
+// connected displays that aren't in it are left as they are. The restore itself isn't
+// added to the history.
+func (a *App) RestoreHistory(n int) error {
+	return withApplyLock(func() error {
+		h, err := state.LoadHistory()
+		if err != nil {
+			return fmt.Errorf("loading layout history: %w", err)
+		}
+
+		if n < 0 || n >= len(h) {
+			return fmt.Errorf("no layout at index %d; history has %d entries", n, len(h))
+		}
+		e := h[n]
+
+		o, err := a.getOutputs()
+		if err != nil {
+			return fmt.Errorf("getting output info: %w", err)
+		}
+
+		// monitors are matched by identity, so a monitor plugged into another port still
+		// gets its layout back
+		layout := map[string]displayPayload{}
+		for _, m := range e.Monitors {
+			layout[m.Identity()] = displayPayload{out: m.Layout(), enable: !m.Disabled}
+		}
+
+		var payloads []displayPayload
+		for _, m := range o.displays {
+			p, ok := layout[m.Identity()]
+			if !ok {
+				p = displayPayload{out: m.Layout(), enable: !m.Disabled}
+			}
+			p.in = m
+			p.out.Name = m.Name
+			p.update = displayUpdateNeeded(&p)
+			payloads = append(payloads, p)
+		}
+
+		slog.Info("restoring layout from history", "index", n, "time", e.Time, "status", e.Status)
+		return a.apply(o, config.Status(e.Status), a.ensureDisplayEnabled(payloads), "")
+	})
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSourceDepth limits how deep "source" lines are followed in Hyprland config files,
+// in case they source each other.
+const maxSourceDepth = 10
+
+// hyprConfLine is a "key = value" line from a Hyprland config file.
+type hyprConfLine struct {
+	key   string
+	value string
+}
+
+// hyprlandConfEnv overrides the path of hyprland.conf, for setups that start Hyprland
+// with --config.
+const hyprlandConfEnv = "HYPRLAND_CONFIG"
+
+// hyprConfDir returns the directory Hyprland and hypridle read their configs from.
+func hyprConfDir() (string, error) {
+	uc, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user config directory path: %w", err)
+	}
+
+	return filepath.Join(uc, "hypr"), nil
+}
+
+// hyprlandConfPath returns the path of hyprland.conf, from $HYPRLAND_CONFIG if it's set.
+func hyprlandConfPath() (string, error) {
+	if p := os.Getenv(hyprlandConfEnv); p != "" {
+		return p, nil
+	}
+
+	dir, err := hyprConfDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "hyprland.conf"), nil
+}
+
+// readHyprConf reads the "key = value" lines of a Hyprland config file (the format
+// hypridle uses too), following "source" lines into other files. Comments and lines
+// without a value, like section headers, are left out.
+func readHyprConf(path string) ([]hyprConfLine, error) {
+	return readHyprConfDepth(path, 0, map[string]string{})
+}
+
+// readHyprConfDepth reads one file of a Hyprland config. vars holds the "$name = value"
+// variables defined so far, which source paths may use.
+func readHyprConfDepth(path string, depth int, vars map[string]string) ([]hyprConfLine, error) {
+	if depth > maxSourceDepth {
+		return nil, fmt.Errorf("%s: sources nested more than %d deep", path, maxSourceDepth)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var lines []hyprConfLine
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(stripHyprComment(sc.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		l := hyprConfLine{key: strings.TrimSpace(key), value: strings.TrimSpace(value)}
+		if name, ok := strings.CutPrefix(l.key, "$"); ok {
+			vars[name] = l.value
+		}
+
+		if l.key != "source" {
+			lines = append(lines, l)
+			continue
+		}
+
+		sourced, err := sourcedFiles(filepath.Dir(path), l.value, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range sourced {
+			sl, err := readHyprConfDepth(s, depth+1, vars)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, sl...)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return lines, nil
+}
+
+// stripHyprComment removes a comment from a line. As in Hyprland, "##" is an escaped "#"
+// rather than the start of a comment.
+func stripHyprComment(line string) string {
+	if !strings.Contains(line, "#") {
+		return line
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' {
+			if i+1 < len(line) && line[i+1] == '#' {
+				b.WriteByte('#')
+				i++
+				continue
+			}
+			break
+		}
+		b.WriteByte(line[i])
+	}
+
+	return b.String()
+}
+
+// sourcedFiles returns the files a "source" line names, which may start with ~, use
+// Hyprland or environment variables, be relative to the sourcing file, or be a glob.
+func sourcedFiles(dir, pattern string, vars map[string]string) ([]string, error) {
+	pattern = os.Expand(pattern, func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return os.Getenv(name)
+	})
+
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("getting home directory: %w", err)
+		}
+		pattern = filepath.Join(home, rest)
+	}
+
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("bad source pattern '%s': %w", pattern, err)
+	}
+
+	return files, nil
+}
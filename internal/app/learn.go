@@ -0,0 +1,147 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+const (
+	// learnInterval is how often learn mode checks the live layout.
+	learnInterval = 2 * time.Second
+	// defaultLearnStableSeconds is how long a rearrangement must stay unchanged before
+	// learn mode saves it.
+	defaultLearnStableSeconds = 10
+)
+
+// learnTracker follows the arrangement of a set of unknown displays to notice when the
+// user rearranges them.
+type learnTracker struct {
+	// unknown identifies the set of unknown displays being tracked.
+	unknown string
+	// baseline is the arrangement as first seen, or as left by hyprlaptop's last change.
+	baseline string
+	// candidate is the latest arrangement that differs from the baseline, and since is
+	// when it was first seen.
+	candidate string
+	since     time.Time
+}
+
+// resetLearnBaseline makes learn mode take the next arrangement it sees as the baseline,
+// so changes hyprlaptop makes itself aren't learned.
+func (a *App) resetLearnBaseline() {
+	a.learn.baseline = ""
+	a.learn.candidate = ""
+}
+
+// checkLearn saves the live layout of unknown displays to the config once the user has
+// rearranged them and the arrangement has been stable for the configured time. Existing
+// config entries are never changed.
+func (a *App) checkLearn() {
+	if !a.Cfg.Learn.Enabled {
+		return
+	}
+
+	current, err := a.Hctl.ListMonitors()
+	if err != nil {
+		slog.Debug("learn mode: listing current displays", "error", err)
+		return
+	}
+
+	var unknown, known []hypr.Monitor
+	var ids []string
+	for _, m := range current {
+		switch {
+		case m.Disabled:
+		case a.isKnownDisplay(m):
+			known = append(known, m)
+		default:
+			unknown = append(unknown, m)
+			ids = append(ids, m.Identity())
+		}
+	}
+
+	if len(unknown) == 0 {
+		a.learn = learnTracker{}
+		return
+	}
+
+	slices.Sort(ids)
+	key := strings.Join(ids, "|")
+	fp := layoutFingerprint(unknown, known)
+	now := time.Now()
+
+	switch {
+	case key != a.learn.unknown:
+		a.learn = learnTracker{unknown: key, baseline: fp}
+		return
+	case a.learn.baseline == "" || a.selfChanges.recent():
+		a.learn.baseline = fp
+		a.learn.candidate = ""
+		return
+	case fp == a.learn.baseline:
+		a.learn.candidate = ""
+		return
+	case fp != a.learn.candidate:
+		slog.Debug("learn mode: displays rearranged; waiting for them to settle")
+		a.learn.candidate = fp
+		a.learn.since = now
+		return
+	}
+
+	stable := time.Duration(a.Cfg.Learn.StableSeconds) * time.Second
+	if stable <= 0 {
+		stable = defaultLearnStableSeconds * time.Second
+	}
+
+	if now.Sub(a.learn.since) < stable {
+		return
+	}
+
+	for _, m := range unknown {
+		if _, ok := a.Cfg.ExternalDisplays[m.Name]; ok {
+			continue
+		}
+
+		if a.Cfg.ExternalDisplays == nil {
+			a.Cfg.ExternalDisplays = map[string]hypr.Monitor{}
+		}
+		a.Cfg.ExternalDisplays[m.Name] = m.Layout()
+		slog.Info("learn mode: saving new display to config", "name", m.Name, "description", m.Description,
+			"width", m.Width, "height", m.Height, "x", m.X, "y", m.Y, "scale", m.Scale)
+	}
+
+	if err := a.Cfg.Write(); err != nil {
+		slog.Error("learn mode: writing config", "error", err)
+	}
+
+	a.learn = learnTracker{}
+}
+
+// layoutFingerprint returns a string describing the layout of the unknown displays, with
+// their positions relative to the top-left corner of the known displays. Only changes to
+// the unknown displays' own layout or placement change it.
+func layoutFingerprint(unknown, known []hypr.Monitor) string {
+	var originX, originY int64
+	for i, m := range known {
+		if i == 0 {
+			originX, originY = m.X, m.Y
+			continue
+		}
+		originX, originY = min(originX, m.X), min(originY, m.Y)
+	}
+
+	var parts []string
+	for _, m := range unknown {
+		l := m.Layout()
+		l.X, l.Y = l.X-originX, l.Y-originY
+		parts = append(parts, fmt.Sprintf("%+v", l))
+	}
+	slices.Sort(parts)
+
+	return strings.Join(parts, ";")
+}
@@ -1,13 +1,17 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
 const (
-	lidStateFile = "/proc/acpi/button/lid/LID/state"
+	acpiLidStateGlob = "/proc/acpi/button/lid/LID*/state"
 )
 
 type lidState string
@@ -18,19 +22,99 @@ const (
 	lidStateClosed  lidState = "Closed"
 )
 
-func getLidState() (lidState, error) {
-	b, err := os.ReadFile(lidStateFile)
+var errNoLidStateSource = errors.New("no lid state source available")
+
+// lidStateProvider is one source of the lid state. Providers are tried in order until
+// one of them returns a known state.
+type lidStateProvider struct {
+	name  string
+	state func() (lidState, error)
+}
+
+func (a *App) lidStateProviders() []lidStateProvider {
+	return []lidStateProvider{
+		{name: "acpi", state: acpiLidState},
+		{name: "logind", state: logindLidState},
+		{name: "bind", state: func() (lidState, error) { return a.bindLidState, nil }},
+		{name: "last_known", state: func() (lidState, error) { return a.lastLidState, nil }},
+	}
+}
+
+// getLidState walks the lid state providers and returns the first known state, along
+// with the name of the provider it came from.
+func (a *App) getLidState() (lidState, string) {
+	for _, p := range a.lidStateProviders() {
+		ls, err := p.state()
+		if err != nil {
+			slog.Debug("lid state provider unavailable", "provider", p.name, "error", err)
+			continue
+		}
+
+		if ls == lidStateUnknown || ls == "" {
+			continue
+		}
+
+		a.lastLidState = ls
+		return ls, p.name
+	}
+
+	return lidStateUnknown, ""
+}
+
+// acpiLidState reads the lid state from the first ACPI lid device, which is usually
+// LID or LID0 depending on the firmware.
+func acpiLidState() (lidState, error) {
+	files, err := filepath.Glob(acpiLidStateGlob)
+	if err != nil {
+		return lidStateUnknown, fmt.Errorf("finding acpi lid state file: %w", err)
+	}
+
+	if len(files) == 0 {
+		return lidStateUnknown, errNoLidStateSource
+	}
+
+	b, err := os.ReadFile(files[0])
 	if err != nil {
 		return lidStateUnknown, fmt.Errorf("reading lid state file: %w", err)
 	}
-	s := strings.ToLower(string(b))
 
-	switch {
-	case strings.Contains(s, "open"):
-		return lidStateOpen, nil
-	case strings.Contains(s, "closed"):
+	return parseLidState(string(b)), nil
+}
+
+// logindLidState reads the LidClosed property from logind via busctl.
+func logindLidState() (lidState, error) {
+	bp, err := exec.LookPath("busctl")
+	if err != nil {
+		return lidStateUnknown, errNoLidStateSource
+	}
+
+	out, err := exec.Command(bp, "get-property", "org.freedesktop.login1", "/org/freedesktop/login1",
+		"org.freedesktop.login1.Manager", "LidClosed").Output()
+	if err != nil {
+		return lidStateUnknown, fmt.Errorf("getting logind LidClosed property: %w", err)
+	}
+
+	// output is in the form "b true"
+	switch strings.TrimSpace(string(out)) {
+	case "b true":
 		return lidStateClosed, nil
+	case "b false":
+		return lidStateOpen, nil
 	default:
 		return lidStateUnknown, nil
 	}
 }
+
+// parseLidState parses lid states from the acpi state file or the lid command.
+func parseLidState(s string) lidState {
+	s = strings.ToLower(s)
+
+	switch {
+	case strings.Contains(s, "open"):
+		return lidStateOpen
+	case strings.Contains(s, "close"):
+		return lidStateClosed
+	default:
+		return lidStateUnknown
+	}
+}
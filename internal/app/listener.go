@@ -2,8 +2,11 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/dsrosen6/hyprlaptop/internal/listener"
 )
@@ -16,6 +19,7 @@ func (a *App) Listen(ctx context.Context) error {
 
 	events := make(chan listener.Event, 16)
 	errc := make(chan error, 1)
+	a.events, a.done = events, ctx.Done()
 
 	go func() {
 		if err := listener.ListenForEvents(ctx, a.Cfg.Path(), events); err != nil {
@@ -24,6 +28,19 @@ func (a *App) Listen(ctx context.Context) error {
 		}
 	}()
 
+	// always run once at startup; the previous state is used to restore anything that
+	// changed while no listener was running
+	a.startDaemonState()
+	defer a.stopDaemonState()
+	a.checkConfig()
+
+	if err := a.Run("STARTUP"); err != nil {
+		slog.Error("running display updater (startup)", "error", err)
+	}
+
+	learnTicker := time.NewTicker(learnInterval)
+	defer learnTicker.Stop()
+
 	for {
 		select {
 		case ev, ok := <-events:
@@ -31,29 +48,29 @@ func (a *App) Listen(ctx context.Context) error {
 				return nil // normal shutdown
 			}
 
+			if a.selfChanges.selfInduced(ev) {
+				slog.Debug("received self-induced event from listener; ignoring",
+					"type", ev.Type, "details", ev.Details, "self_induced", true)
+				continue
+			}
+
 			slog.Info("received event from listener", "type", ev.Type, "details", ev.Details)
-			switch ev.Type {
-			// All of these do the same thing. They are separate events for logging and for potential
-			// logic if they need to do different things in the future.
-			case listener.DisplayAddEvent, listener.DisplayRemoveEvent, listener.LidSwitchEvent,
-				listener.IdleWakeEvent, listener.DisplayUnknownEvent:
-				if err := a.Run(); err != nil {
-					slog.Error("running display updater", "error", err)
-				}
-
-			case listener.ConfigUpdatedEvent:
-				// Update config values
-				err := a.Cfg.Reload(5)
-				if err != nil {
-					slog.Error("reloading config", "error", err)
-				} else {
-					// Run displayer updater in case changes are needed from new config values
-					if err := a.Run(); err != nil {
-						slog.Error("running display updater (config change)", "error", err)
-					}
-				}
+			var res any
+			err := a.checkRequestConfig(ev)
+			if err == nil {
+				res, err = a.handleEvent(ev)
+			}
+			if err != nil {
+				slog.Error("handling event", "type", ev.Type, "error", err)
 			}
 
+			if ev.Reply != nil {
+				ev.Reply <- newResponse(res, err)
+			}
+
+		case <-learnTicker.C:
+			a.checkLearn()
+
 		case err := <-errc:
 			return fmt.Errorf("listener failed: %w", err)
 
@@ -62,3 +79,128 @@ func (a *App) Listen(ctx context.Context) error {
 		}
 	}
 }
+
+// handleEvent handles a single event, whether it came from the listener or was run
+// locally by the CLI because the listener isn't running. The result, if any, is sent
+// back to the CLI.
+func (a *App) handleEvent(ev listener.Event) (any, error) {
+	switch ev.Type {
+	// All of these do the same thing. They are separate events for logging and for potential
+	// logic if they need to do different things in the future.
+	case listener.DisplayAddEvent, listener.DisplayRemoveEvent, listener.LidSwitchEvent,
+		listener.IdleWakeEvent, listener.DisplayUnknownEvent, listener.RefreshEvent:
+		switch ev.Type {
+		case listener.LidSwitchEvent:
+			// a bind without a state still means the lid moved, so the old one is stale
+			a.bindLidState = lidStateUnknown
+			if len(ev.Args) > 0 {
+				a.bindLidState = parseLidState(ev.Args[0])
+			}
+		case listener.IdleWakeEvent:
+			// the lid may have moved while asleep without the bind firing
+			a.bindLidState = lidStateUnknown
+		}
+
+		if err := a.Run(string(ev.Type)); err != nil {
+			return nil, fmt.Errorf("running display updater: %w", err)
+		}
+
+		return nil, nil
+
+	case listener.ConfigUpdatedEvent:
+		// Swap in the new config, unless it is invalid
+		if err := a.reloadConfig(); err != nil {
+			return nil, fmt.Errorf("reloading config: %w", err)
+		}
+
+		// Run displayer updater in case changes are needed from new config values
+		if err := a.Run(string(ev.Type)); err != nil {
+			return nil, fmt.Errorf("running display updater (config change): %w", err)
+		}
+
+		return nil, nil
+
+	case listener.SaveDisplaysEvent:
+		var opts SaveDisplaysOptions
+		if len(ev.Args) > 0 {
+			if err := json.Unmarshal([]byte(ev.Args[0]), &opts); err != nil {
+				return nil, fmt.Errorf("invalid save-displays options: %w", err)
+			}
+		}
+
+		res, err := a.SaveCurrentDisplays(opts)
+		if err != nil {
+			return nil, fmt.Errorf("saving displays: %w", err)
+		}
+
+		return res, nil
+
+	case listener.RestoreLayoutEvent:
+		n := 1 // undo by default, i.e. the layout before the current one
+		if len(ev.Args) > 0 {
+			i, err := strconv.Atoi(ev.Args[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid history index '%s'", ev.Args[0])
+			}
+			n = i
+		}
+
+		if err := a.RestoreHistory(n); err != nil {
+			return nil, fmt.Errorf("restoring layout: %w", err)
+		}
+
+		return nil, nil
+
+	case listener.SetModeEvent:
+		if len(ev.Args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument (mode), got %d", len(ev.Args))
+		}
+
+		mode, err := parseMode(ev.Args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if err := a.SetMode(mode); err != nil {
+			return nil, fmt.Errorf("setting mode: %w", err)
+		}
+
+		return ModeResult{Mode: string(mode)}, nil
+
+	case listener.CycleModeEvent:
+		mode, err := a.CycleMode()
+		if err != nil {
+			return nil, fmt.Errorf("cycling mode: %w", err)
+		}
+
+		return ModeResult{Mode: string(mode)}, nil
+
+	case listener.UnknownDisplayChoiceEvent:
+		if len(ev.Args) != 3 {
+			return nil, fmt.Errorf("expected 3 arguments (name, identity, choice), got %d", len(ev.Args))
+		}
+
+		if err := a.handleUnknownDisplayChoice(ev.Args[0], ev.Args[1], ev.Args[2]); err != nil {
+			return nil, fmt.Errorf("applying unknown display choice: %w", err)
+		}
+
+		return nil, nil
+
+	case listener.SetProfileEvent:
+		if len(ev.Args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument (profile name), got %d", len(ev.Args))
+		}
+
+		if err := a.SetProfile(ev.Args[0]); err != nil {
+			return nil, fmt.Errorf("setting profile: %w", err)
+		}
+
+		return ModeResult{Mode: string(modeProfile), Profile: ev.Args[0]}, nil
+
+	case listener.StatusEvent:
+		return a.Status(), nil
+
+	default:
+		return nil, fmt.Errorf("unhandled event type '%s'", ev.Type)
+	}
+}
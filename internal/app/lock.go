@@ -0,0 +1,44 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/dsrosen6/hyprlaptop/internal/listener"
+)
+
+const lockFileName = "hyprlaptop.lock"
+
+// withApplyLock runs fn while holding an exclusive lock on the apply lock file, so that
+// the listener and any CLI fallbacks never run hyprctl sequences at the same time.
+func withApplyLock(fn func() error) error {
+	f, err := os.OpenFile(lockFilePath(), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening lock file: %w", err)
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			slog.Error("closing lock file", "error", err)
+		}
+	}()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+
+	defer func() {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+			slog.Error("releasing lock", "error", err)
+		}
+	}()
+
+	return fn()
+}
+
+func lockFilePath() string {
+	return filepath.Join(listener.RuntimeDir(), lockFileName)
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MenuEntry is a layout that can be picked from a launcher menu.
+type MenuEntry struct {
+	Label string `json:"label"`
+	// Kind is either "mode" or "profile", and Value the mode or profile name.
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// MenuEntries returns the modes and profiles that fit the connected displays. Modes other
+// than laptop and auto are only listed if an external display is connected, and present
+// only if one of them isn't in the config.
+func (a *App) MenuEntries() ([]MenuEntry, error) {
+	o, err := a.getOutputs()
+	if err != nil {
+		return nil, fmt.Errorf("getting output info: %w", err)
+	}
+
+	modes := []displayMode{modeLaptop, modeAuto}
+	if o.externalCount() > 0 {
+		modes = slices.Concat(cycleModes, []displayMode{modeAuto})
+		for _, m := range o.displays {
+			if !a.isKnownDisplay(m) {
+				modes = slices.Insert(modes, len(modes)-1, modePresent)
+				break
+			}
+		}
+	}
+
+	var entries []MenuEntry
+	for _, m := range modes {
+		entries = append(entries, MenuEntry{
+			Label: fmt.Sprintf("mode: %s", m),
+			Kind:  "mode",
+			Value: string(m),
+		})
+	}
+
+	for _, name := range a.fittingProfiles(o) {
+		entries = append(entries, MenuEntry{
+			Label: fmt.Sprintf("profile: %s", name),
+			Kind:  "profile",
+			Value: name,
+		})
+	}
+
+	return entries, nil
+}
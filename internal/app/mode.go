@@ -0,0 +1,141 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// displayMode is a manual override of the automatic lid/hotplug status, similar to the
+// projection modes on Windows.
+type displayMode string
+
+const (
+	modeAuto     displayMode = "auto"
+	modeExtend   displayMode = "extend"
+	modeMirror   displayMode = "mirror"
+	modeExternal displayMode = "external"
+	modeLaptop   displayMode = "laptop"
+	// modePresent mirrors the laptop display onto any display not in the config.
+	modePresent displayMode = "present"
+	// modeProfile applies a named profile from the config; it can't be set directly.
+	modeProfile displayMode = "profile"
+)
+
+// cycleModes is the order the cycle command steps through.
+var cycleModes = []displayMode{modeExtend, modeMirror, modeExternal, modeLaptop}
+
+// modeOverride is a mode set by the user, along with the monitors connected when it was
+// set. It is cleared as soon as the connected monitors change.
+type modeOverride struct {
+	mode      displayMode
+	profile   string
+	connected []string
+}
+
+// ModeResult is the data returned by the mode and cycle commands.
+type ModeResult struct {
+	Mode    string `json:"mode"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// ModeNames returns the modes that can be set with the mode command.
+func ModeNames() []string {
+	var names []string
+	for _, m := range cycleModes {
+		names = append(names, string(m))
+	}
+
+	return append(names, string(modePresent), string(modeAuto))
+}
+
+func parseMode(s string) (displayMode, error) {
+	m := displayMode(s)
+	if m == modeAuto || m == modePresent || slices.Contains(cycleModes, m) {
+		return m, nil
+	}
+
+	return "", fmt.Errorf("invalid mode '%s'; must be one of extend, mirror, external, laptop, present or auto", s)
+}
+
+// currentMode returns the active mode override, or auto if there is none.
+func (a *App) currentMode() displayMode {
+	if a.override == nil {
+		return modeAuto
+	}
+
+	return a.override.mode
+}
+
+// SetMode sets or, with "auto", clears the mode override, then runs the display updater.
+func (a *App) SetMode(mode displayMode) error {
+	if mode == modeAuto {
+		a.override = nil
+		slog.Info("mode override cleared")
+		return a.Run("MODE " + string(mode))
+	}
+
+	current, err := a.Hctl.ListMonitors()
+	if err != nil {
+		return fmt.Errorf("listing current displays: %w", err)
+	}
+
+	a.override = &modeOverride{
+		mode:      mode,
+		connected: connectedIdentities(current),
+	}
+	slog.Info("mode override set", "mode", mode)
+
+	return a.Run("MODE " + string(mode))
+}
+
+// CycleMode switches to the mode after the current one.
+func (a *App) CycleMode() (displayMode, error) {
+	next := cycleModes[0]
+	if i := slices.Index(cycleModes, a.currentMode()); i != -1 {
+		next = cycleModes[(i+1)%len(cycleModes)]
+	}
+
+	return next, a.SetMode(next)
+}
+
+// activeOverride returns the mode override if one is set, clearing it first if the
+// connected monitors have changed since it was set.
+func (a *App) activeOverride(o *getOutputResult) *modeOverride {
+	if a.override == nil {
+		return nil
+	}
+
+	if !slices.Equal(a.override.connected, connectedIdentities(o.displays)) {
+		slog.Info("connected displays changed; clearing mode override", "mode", a.override.mode)
+		a.override = nil
+		return nil
+	}
+
+	if a.override.mode == modeProfile {
+		if _, ok := a.Cfg.Profiles[a.override.profile]; !ok {
+			slog.Info("profile no longer in config; clearing mode override", "profile", a.override.profile)
+			a.override = nil
+			return nil
+		}
+	}
+
+	return a.override
+}
+
+// modeDisplays returns which displays a mode enables, and whether the externals should
+// mirror the laptop display.
+func modeDisplays(mode displayMode) (enableLaptop, enableExternals, mirror bool) {
+	switch mode {
+	case modeExtend, modePresent:
+		return true, true, false
+	case modeMirror:
+		return true, true, true
+	case modeExternal:
+		return false, true, false
+	case modeLaptop:
+		return true, false, false
+	default:
+		return false, false, false
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+type (
+	// MonitorInfo is a connected monitor and how the config sees it, for the monitors
+	// command.
+	MonitorInfo struct {
+		// Monitor is the monitor as Hyprland reports it.
+		Monitor hypr.Monitor `json:"monitor"`
+		Laptop  bool         `json:"laptop"`
+		// ConfigEntry is where the monitor is in the config, "laptop_display" or
+		// "external_displays.<name>", or empty if it isn't.
+		ConfigEntry string        `json:"config_entry,omitempty"`
+		Configured  *hypr.Monitor `json:"configured,omitempty"`
+		// Diff is how the live layout differs from the configured one, from live to
+		// configured.
+		Diff     []hypr.FieldDiff `json:"diff,omitempty"`
+		Profiles []MonitorProfile `json:"profiles,omitempty"`
+	}
+
+	// MonitorProfile is a profile a monitor is in, and how it differs from it.
+	MonitorProfile struct {
+		Name       string           `json:"name"`
+		Configured hypr.Monitor     `json:"configured"`
+		Diff       []hypr.FieldDiff `json:"diff,omitempty"`
+	}
+)
+
+// Monitors returns every connected monitor, enabled or not, sorted by name, along with
+// the config entry and profiles it matches.
+func (a *App) Monitors() ([]MonitorInfo, error) {
+	current, err := a.Hctl.ListMonitors()
+	if err != nil {
+		return nil, fmt.Errorf("listing current displays: %w", err)
+	}
+
+	infos := []MonitorInfo{}
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		m := current[name]
+		info := MonitorInfo{Monitor: m, Laptop: a.isLaptopDisplay(m)}
+		if c, ok := a.getDisplayFromConfig(m); ok {
+			info.ConfigEntry = "external_displays." + m.Name
+			if info.Laptop {
+				info.ConfigEntry = "laptop_display"
+			}
+			info.Configured = &c
+			info.Diff = m.Diff(c)
+		}
+
+		for _, p := range slices.Sorted(maps.Keys(a.Cfg.Profiles)) {
+			if c, ok := a.Cfg.Profiles[p].Displays[m.Name]; ok {
+				info.Profiles = append(info.Profiles, MonitorProfile{Name: p, Configured: c, Diff: m.Diff(c)})
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
@@ -0,0 +1,221 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+	"github.com/dsrosen6/hyprlaptop/internal/listener"
+)
+
+const notifyAppName = "hyprlaptop"
+
+// notifyActionsVersion is the first version of notify-send (libnotify) with --action.
+// Older versions don't support action buttons at all.
+var notifyActionsVersion = [3]int{0, 7, 10}
+
+var errNoNotifyActions = errors.New("notify-send is too old for action buttons (needs libnotify 0.7.10 or newer)")
+
+// Actions offered in the notification for an unknown display.
+const (
+	choiceExtendRight = "extend-right"
+	choiceExtendLeft  = "extend-left"
+	choiceMirror      = "mirror"
+	choiceExternal    = "external"
+	choiceIgnore      = "ignore"
+)
+
+// notifyAction is an action button on a notification.
+type notifyAction struct {
+	key   string
+	label string
+}
+
+var unknownDisplayActions = []notifyAction{
+	{key: choiceExtendRight, label: "Extend right"},
+	{key: choiceExtendLeft, label: "Extend left"},
+	{key: choiceMirror, label: "Mirror"},
+	{key: choiceExternal, label: "External only"},
+	{key: choiceIgnore, label: "Ignore"},
+}
+
+// notify sends a desktop notification via notify-send, which uses the
+// org.freedesktop.Notifications interface. If actions are given, it blocks until the
+// notification is closed and returns the key of the action picked, if any; it returns
+// errNoNotifyActions without sending anything if notify-send doesn't support them.
+func notify(summary, body string, actions ...notifyAction) (string, error) {
+	bp, err := exec.LookPath("notify-send")
+	if err != nil {
+		return "", fmt.Errorf("finding notify-send: %w", err)
+	}
+
+	if len(actions) > 0 && !notifySupportsActions(bp) {
+		return "", errNoNotifyActions
+	}
+
+	args := []string{"--app-name=" + notifyAppName}
+	for _, a := range actions {
+		args = append(args, fmt.Sprintf("--action=%s=%s", a.key, a.label))
+	}
+	args = append(args, summary, body)
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(bp, args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("sending notification: %w", err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// notifySupportsActions reports whether notify-send is new enough for action buttons,
+// from its version output, e.g. "notify-send 0.8.3".
+func notifySupportsActions(bp string) bool {
+	out, err := exec.Command(bp, "--version").Output()
+	if err != nil {
+		return false
+	}
+
+	var v [3]int
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return false
+	}
+	if _, err := fmt.Sscanf(fields[len(fields)-1], "%d.%d.%d", &v[0], &v[1], &v[2]); err != nil {
+		return false
+	}
+
+	for i := range v {
+		if v[i] != notifyActionsVersion[i] {
+			return v[i] > notifyActionsVersion[i]
+		}
+	}
+
+	return true
+}
+
+// askAboutUnknownDisplay notifies the user once about a connected display that isn't in
+// the config, and otherwise leaves it as Hyprland set it up. When running as the listener,
+// the notification has actions, and the one picked is sent back as an event.
+func (a *App) askAboutUnknownDisplay(m hypr.Monitor) {
+	if a.asked[m.Identity()] {
+		return
+	}
+	a.asked[m.Identity()] = true
+
+	summary := "Unknown display connected"
+	plain := func() {
+		body := fmt.Sprintf("%s (%s) isn't in the config. Run 'hyprlaptop menu' or 'hyprlaptop mode' to choose a layout.",
+			m.Name, m.Description)
+		if _, err := notify(summary, body); err != nil {
+			slog.Warn("notifying about unknown display", "name", m.Name, "error", err)
+		}
+	}
+
+	if a.events == nil {
+		plain()
+		return
+	}
+
+	// the notification can stay up long after the listener has stopped, so the choice is
+	// dropped rather than sent once the listener is done
+	events, done := a.events, a.done
+	body := fmt.Sprintf("%s (%s) isn't in the config. What should hyprlaptop do with it?", m.Name, m.Description)
+	go func() {
+		choice, err := notify(summary, body, unknownDisplayActions...)
+		if errors.Is(err, errNoNotifyActions) {
+			slog.Warn("can't ask about unknown display", "name", m.Name, "error", err)
+			plain()
+			return
+		}
+		if err != nil {
+			slog.Warn("notifying about unknown display", "name", m.Name, "error", err)
+			return
+		}
+
+		if choice == "" {
+			slog.Debug("unknown display notification dismissed", "name", m.Name)
+			return
+		}
+
+		select {
+		case events <- listener.Event{
+			Type:    listener.UnknownDisplayChoiceEvent,
+			Details: fmt.Sprintf("%s %s", m.Name, choice),
+			Args:    []string{m.Name, m.Identity(), choice},
+		}:
+		case <-done:
+			slog.Debug("listener stopped; dropping unknown display choice", "name", m.Name, "choice", choice)
+		}
+	}()
+}
+
+// handleUnknownDisplayChoice applies the action the user picked for an unknown display
+// and, if the config asks for it, saves the result to the config.
+func (a *App) handleUnknownDisplayChoice(name, identity, choice string) error {
+	cfg := a.Cfg.UnknownDisplays
+	switch choice {
+	case choiceExtendRight, choiceExtendLeft:
+		opts := cfg.Extend
+		opts.Position = strings.TrimPrefix(choice, "extend-")
+		a.unknownChoices[identity] = unknownChoice{action: config.UnknownDisplayExtend, opts: opts}
+	case choiceMirror:
+		a.unknownChoices[identity] = unknownChoice{action: config.UnknownDisplayMirror, opts: cfg.Mirror}
+	case choiceExternal:
+		a.unknownChoices[identity] = unknownChoice{action: config.UnknownDisplayIgnore}
+	case choiceIgnore:
+		a.unknownChoices[identity] = unknownChoice{action: config.UnknownDisplayIgnore}
+	default:
+		return fmt.Errorf("unknown choice '%s'", choice)
+	}
+	slog.Info("unknown display choice received", "name", name, "choice", choice)
+
+	var err error
+	if choice == choiceExternal {
+		err = a.SetMode(modeExternal)
+	} else {
+		err = a.Run(string(listener.UnknownDisplayChoiceEvent))
+	}
+	if err != nil {
+		return err
+	}
+
+	if !cfg.SaveChoice {
+		return nil
+	}
+
+	return a.saveDisplayToConfig(name)
+}
+
+// saveDisplayToConfig saves the live layout of a display to the config's external
+// displays, so it is no longer treated as unknown.
+func (a *App) saveDisplayToConfig(name string) error {
+	current, err := a.Hctl.ListMonitors()
+	if err != nil {
+		return fmt.Errorf("listing current displays: %w", err)
+	}
+
+	m, ok := current[name]
+	if !ok || m.Disabled {
+		return fmt.Errorf("display '%s' is no longer connected and enabled", name)
+	}
+
+	if a.Cfg.ExternalDisplays == nil {
+		a.Cfg.ExternalDisplays = map[string]hypr.Monitor{}
+	}
+	a.Cfg.ExternalDisplays[name] = m.Layout()
+	delete(a.unknownChoices, m.Identity())
+
+	if err := a.Cfg.Write(); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+
+	slog.Info("unknown display saved to config", "name", name)
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/dsrosen6/hyprlaptop/internal/config"
 	"github.com/dsrosen6/hyprlaptop/internal/hypr"
 )
 
@@ -16,19 +17,16 @@ type (
 		displays   hypr.MonitorMap
 		lidState   lidState
 	}
-
-	outputsStatus string
 )
 
-const (
-	statusUnknown outputsStatus = "UNKNOWN"
-	statusOLLC    outputsStatus = "ONLY_LAPTOP_LID_CLOSED"
-	statusOLLO    outputsStatus = "ONLY_LAPTOP_LID_OPEN"
-	statusWELC    outputsStatus = "WITH_EXTERNAL_LID_CLOSED"
-	statusWELO    outputsStatus = "WITH_EXTERNAL_LID_OPEN"
-)
+// Run checks the current displays and lid state and applies any changes needed to match
+// the config. It holds the apply lock for its whole duration. The trigger is the event
+// that caused the run, and is recorded in the layout history.
+func (a *App) Run(trigger string) error {
+	return withApplyLock(func() error { return a.run(trigger) })
+}
 
-func (a *App) Run() error {
+func (a *App) run(trigger string) error {
 	o, err := a.getOutputs()
 	if err != nil {
 		return fmt.Errorf("getting output info: %w", err)
@@ -37,7 +35,14 @@ func (a *App) Run() error {
 	s := o.statusShouldBe()
 	slog.Info(fmt.Sprintf("status received: %s", s))
 
-	payloads := a.createPayloads(o, s)
+	return a.apply(o, s, a.createPayloads(o, s), trigger)
+}
+
+// apply applies a set of payloads covering every connected display, saving and restoring
+// workspace placements around it and recording it in the layout history under trigger.
+// Restores pass an empty trigger and aren't recorded, so a second undo doesn't flip back
+// to the layout that was undone.
+func (a *App) apply(o *getOutputResult, s config.Status, payloads []displayPayload, trigger string) error {
 	needUpdate := false
 	for _, p := range payloads {
 		slog.Debug("display detected", logDisplayAttr(p))
@@ -46,35 +51,125 @@ func (a *App) Run() error {
 		}
 	}
 
+	// monitors that came back on their own (e.g. redocking) get their workspaces back
+	// before anything else is snapshotted
+	if err := a.restorePlacements(a.replugged(o)); err != nil {
+		slog.Warn("restoring workspace placements", "error", err)
+	}
+
 	if !needUpdate {
 		slog.Info("no updates needed")
+		a.finishRun(o, s, payloads)
 		return nil
 	}
 
+	var leaving, returning []hypr.Monitor
+	for _, p := range payloads {
+		switch {
+		case p.update && !p.enable && !p.in.Disabled:
+			leaving = append(leaving, p.in)
+		case p.update && p.enable && p.in.Disabled:
+			returning = append(returning, p.in)
+		}
+	}
+
+	if err := a.snapshotPlacements(leaving, enabledIdentities(o)); err != nil {
+		slog.Warn("saving workspace placements", "error", err)
+	}
+
 	if err := a.updateDisplays(payloads); err != nil {
+		a.saveRunState(o, s)
 		return fmt.Errorf("updating displays: %w", err)
 	}
 
+	if err := a.restorePlacements(returning); err != nil {
+		slog.Warn("restoring workspace placements", "error", err)
+	}
+
+	a.finishRun(o, s, payloads)
+	if trigger != "" {
+		a.recordHistory(s, payloads, trigger)
+	}
+	a.resetLearnBaseline()
 	return nil
 }
 
+// finishRun saves workspace placements and listener state after a run.
+func (a *App) finishRun(o *getOutputResult, s config.Status, payloads []displayPayload) {
+	a.refreshPlacements(payloads)
+	a.saveDaemonState(o, s, payloads)
+}
+
+// replugged returns the monitors that are enabled now but weren't after the last run,
+// without hyprlaptop having enabled them.
+func (a *App) replugged(o *getOutputResult) []hypr.Monitor {
+	if a.lastEnabled == nil {
+		return nil
+	}
+
+	var ms []hypr.Monitor
+	for _, m := range o.displays {
+		if !m.Disabled && !a.lastEnabled[m.Identity()] {
+			ms = append(ms, m)
+		}
+	}
+
+	return ms
+}
+
+// refreshPlacements snapshots the workspaces on every monitor left enabled by the run,
+// and saves all placements to the state directory.
+func (a *App) refreshPlacements(payloads []displayPayload) {
+	a.loadPlacements()
+
+	var enabled []hypr.Monitor
+	a.lastEnabled = map[string]bool{}
+	for _, p := range payloads {
+		if p.enable {
+			enabled = append(enabled, p.in)
+			a.lastEnabled[p.in.Identity()] = true
+		}
+	}
+
+	if err := a.snapshotPlacements(enabled, a.lastEnabled); err != nil {
+		slog.Warn("saving workspace placements", "error", err)
+		return
+	}
+
+	if err := a.placements.Save(); err != nil {
+		slog.Warn("writing workspace placements", "error", err)
+	}
+}
+
+// enabledIdentities returns the identities of the currently enabled monitors.
+func enabledIdentities(o *getOutputResult) map[string]bool {
+	ids := map[string]bool{}
+	for _, m := range o.displays {
+		if !m.Disabled {
+			ids[m.Identity()] = true
+		}
+	}
+
+	return ids
+}
+
 func (a *App) getOutputs() (*getOutputResult, error) {
 	current, err := a.Hctl.ListMonitors()
 	if err != nil {
 		return nil, fmt.Errorf("listing current displays: %w", err)
 	}
 
-	var names []string
+	var names, disabled []string
 	for _, m := range current {
 		names = append(names, m.Name)
+		if m.Disabled {
+			disabled = append(disabled, m.Name)
+		}
 	}
-	slog.Info("displays detected", "names", strings.Join(names, ","))
+	slog.Info("displays detected", "names", strings.Join(names, ","), "disabled", strings.Join(disabled, ","))
 
-	ls, err := getLidState()
-	if err != nil {
-		return nil, fmt.Errorf("getting lid status: %w", err)
-	}
-	slog.Debug(fmt.Sprintf("lid state: %s", ls))
+	ls, src := a.getLidState()
+	slog.Debug(fmt.Sprintf("lid state: %s", ls), "source", src)
 
 	return &getOutputResult{
 		laptopName: a.Cfg.LaptopDisplay.Name,
@@ -93,17 +188,20 @@ func (a *App) updateDisplays(displays []displayPayload) error {
 		}
 
 		wg.Add(1)
+		a.selfChanges.record(p.out.Name)
 		go func(p displayPayload) {
 			defer wg.Done()
 			m := p.out
 			if p.enable {
 				if err := a.Hctl.EnableOrUpdateMonitor(m); err != nil {
 					errc <- fmt.Errorf("enabling or updating display %s: %w", m.Name, err)
+					return
 				}
 				slog.Info("display enabled", "name", m.Name)
 			} else {
 				if err := a.Hctl.DisableMonitor(m); err != nil {
 					errc <- fmt.Errorf("disabling display %s: %w", m.Name, err)
+					return
 				}
 				slog.Info("display disabled", "name", m.Name)
 			}
@@ -115,17 +213,14 @@ func (a *App) updateDisplays(displays []displayPayload) error {
 		close(errc)
 	}()
 
-	hasErr := false
+	// joined so the hyprctl errors can still be told apart, e.g. for the exit code
+	var errs []error
 	for err := range errc {
-		hasErr = true
 		slog.Error(err.Error())
+		errs = append(errs, err)
 	}
 
-	if hasErr {
-		return errors.New("failed to update one or more external display; see logs")
-	}
-
-	return nil
+	return errors.Join(errs...)
 }
 
 func (a *App) isLaptopDisplay(m hypr.Monitor) bool {
@@ -146,38 +241,55 @@ func (a *App) getDisplayFromConfig(m hypr.Monitor) (hypr.Monitor, bool) {
 
 // statusShouldBe checks the state of displays and lid status, and returns the status
 // that hyprlaptop should be switched to (if it isn't already)
-func (o *getOutputResult) statusShouldBe() outputsStatus {
-	// check if laptop is the only display
-	if _, ok := o.displays[o.laptopName]; ok && len(o.displays) == 1 {
+func (o *getOutputResult) statusShouldBe() config.Status {
+	// check if laptop is the only connected display, whether or not it is enabled
+	if o.laptopConnected() && o.externalCount() == 0 {
 		return onlyLaptopStates(o.lidState)
 	}
 
 	return withExternalStates(o.lidState)
 }
 
-func onlyLaptopStates(ls lidState) outputsStatus {
+func (o *getOutputResult) laptopConnected() bool {
+	_, ok := o.displays[o.laptopName]
+	return ok
+}
+
+// externalCount returns the number of connected external displays, enabled or not.
+func (o *getOutputResult) externalCount() int {
+	n := 0
+	for name := range o.displays {
+		if name != o.laptopName {
+			n++
+		}
+	}
+
+	return n
+}
+
+func onlyLaptopStates(ls lidState) config.Status {
 	switch ls {
 	case lidStateOpen:
-		return statusOLLO
+		return config.StatusOnlyLaptopLidOpen
 	case lidStateClosed:
-		return statusOLLC
+		return config.StatusOnlyLaptopLidClosed
 	case lidStateUnknown:
-		return statusUnknown
+		return config.StatusUnknown
 	default:
-		return statusUnknown
+		return config.StatusUnknown
 	}
 }
 
-func withExternalStates(ls lidState) outputsStatus {
+func withExternalStates(ls lidState) config.Status {
 	switch ls {
 	case lidStateOpen:
-		return statusWELO
+		return config.StatusWithExternalLidOpen
 	case lidStateClosed:
-		return statusWELC
+		return config.StatusWithExternalLidClosed
 	case lidStateUnknown:
-		return statusUnknown
+		return config.StatusUnknown
 	default:
-		return statusUnknown
+		return config.StatusUnknown
 	}
 }
 
@@ -186,6 +298,8 @@ func logDisplayAttr(p displayPayload) slog.Attr {
 		p.out.Name,
 		slog.Bool("from_config", p.fromConfig),
 		slog.Bool("update_needed", p.update),
+		slog.Bool("enable", p.enable),
+		slog.Bool("was_disabled", p.in.Disabled),
 		slog.Int64("width", p.out.Width),
 		slog.Int64("height", p.out.Height),
 		slog.Float64("refresh_rate", p.out.RefreshRate),
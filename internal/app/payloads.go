@@ -3,7 +3,9 @@ package app
 import (
 	"log/slog"
 	"reflect"
+	"slices"
 
+	"github.com/dsrosen6/hyprlaptop/internal/config"
 	"github.com/dsrosen6/hyprlaptop/internal/hypr"
 )
 
@@ -15,56 +17,76 @@ type displayPayload struct {
 	update     bool
 }
 
-func (a *App) createPayloads(o *getOutputResult, status outputsStatus) []displayPayload {
-	var enableLaptop, enableExternals bool
-	switch status {
-	case statusWELO:
-		enableLaptop = true
-		enableExternals = true
-	case statusOLLO, statusOLLC:
-		enableLaptop = true
-	case statusWELC:
-		enableExternals = true
+func (a *App) createPayloads(o *getOutputResult, status config.Status) []displayPayload {
+	var enableLaptop, enableExternals, mirror bool
+	ov := a.activeOverride(o)
+	if ov != nil && ov.mode == modeProfile {
+		slog.Info("profile override active", "profile", ov.profile)
+		return a.ensureDisplayEnabled(a.profilePayloads(o, a.Cfg.Profiles[ov.profile]))
+	} else if ov != nil {
+		// a manual mode takes precedence over the automatic status
+		enableLaptop, enableExternals, mirror = modeDisplays(ov.mode)
+		slog.Info("mode override active", "mode", ov.mode)
+	} else if p, ok := a.matchPolicy(o, status); ok {
+		enableLaptop = p.EnableLaptop
+		enableExternals = p.EnableExternals
+	} else {
+		slog.Warn("no policy matched status", "status", status)
 	}
 
-	slog.Debug("status processed", "enable_laptop", enableLaptop, "enable_externals", enableExternals)
+	slog.Debug("status processed", "enable_laptop", enableLaptop, "enable_externals", enableExternals, "mirror", mirror)
 	var payloads []displayPayload
 
-	// specific checks for if laptop display needs to be enabled or disabled
-	var lp *displayPayload
-	if enableLaptop && !a.laptopDisplayEnabled(o) {
-		lp = &displayPayload{
-			in:         a.Cfg.LaptopDisplay,
-			out:        a.Cfg.LaptopDisplay,
-			enable:     true,
-			fromConfig: true,
-			update:     true,
+	// every connected display is listed, including disabled ones, so the laptop display
+	// is handled here along with the externals
+	for _, m := range o.displays {
+		p := a.createPayload(m, enableLaptop, enableExternals)
+		if p == nil {
+			continue
 		}
-	} else if !enableLaptop && a.laptopDisplayEnabled(o) {
-		lp = &displayPayload{
-			in:         a.Cfg.LaptopDisplay,
-			out:        a.Cfg.LaptopDisplay,
-			enable:     false,
-			fromConfig: true,
-			update:     true,
+
+		if mirror && p.enable && !a.isLaptopDisplay(m) && o.laptopConnected() {
+			p.out.MirrorOf = o.laptopName
+			p.update = displayUpdateNeeded(p)
 		}
+
+		payloads = append(payloads, *p)
 	}
 
-	if lp != nil {
-		payloads = append(payloads, *lp)
+	a.applyUnknownDisplayPolicy(o, ov, payloads)
+
+	return a.ensureDisplayEnabled(payloads)
+}
+
+// ensureDisplayEnabled guards against a payload set that would leave no display enabled,
+// which leaves Hyprland in an unusable state. If nothing would be enabled, the laptop
+// display is kept on, or the first external if the laptop display isn't connected.
+func (a *App) ensureDisplayEnabled(payloads []displayPayload) []displayPayload {
+	if len(payloads) == 0 {
+		return payloads
 	}
 
-	for _, m := range o.displays {
-		if p := a.createPayload(m, enableLaptop, enableExternals); p != nil {
-			payloads = append(payloads, *p)
+	for _, p := range payloads {
+		if p.enable {
+			return payloads
 		}
 	}
 
-	return payloads
-}
+	i := slices.IndexFunc(payloads, func(p displayPayload) bool { return a.isLaptopDisplay(p.in) })
+	if i == -1 {
+		i = 0
+		for j, p := range payloads {
+			if p.in.Name < payloads[i].in.Name {
+				i = j
+			}
+		}
+	}
 
-func (a *App) laptopDisplayEnabled(o *getOutputResult) bool {
-	return displayEnabled(o, a.Cfg.LaptopDisplay)
+	p := &payloads[i]
+	slog.Warn("payloads would leave no display enabled; keeping one enabled", "name", p.in.Name)
+	p.enable = true
+	p.update = displayUpdateNeeded(p)
+	return payloads
 }
 
 func (a *App) createPayload(in hypr.Monitor, enableLaptop, enableExternals bool) *displayPayload {
@@ -72,10 +94,10 @@ func (a *App) createPayload(in hypr.Monitor, enableLaptop, enableExternals bool)
 		in: in,
 	}
 
-	p.out = in
+	p.out = in.Layout()
 	if c, ok := a.getDisplayFromConfig(in); ok {
 		p.fromConfig = true
-		p.out = c
+		p.out = c.Layout()
 	}
 
 	p.enable = enableExternals
@@ -83,18 +105,20 @@ func (a *App) createPayload(in hypr.Monitor, enableLaptop, enableExternals bool)
 		p.enable = enableLaptop
 	}
 
-	if displayUpdateNeeded(p.in, p.out) {
+	if displayUpdateNeeded(p) {
 		p.update = true
 	}
 
 	return p
 }
 
-func displayUpdateNeeded(a, b hypr.Monitor) bool {
-	return !reflect.DeepEqual(a, b)
-}
+// displayUpdateNeeded reports whether a display's enabled state needs to change, or, if
+// it is to stay enabled, whether its live layout differs from the desired one.
+func displayUpdateNeeded(p *displayPayload) bool {
+	enabled := !p.in.Disabled
+	if enabled != p.enable {
+		return true
+	}
 
-func displayEnabled(o *getOutputResult, m hypr.Monitor) bool {
-	_, ok := o.displays[m.Name]
-	return ok
+	return p.enable && !reflect.DeepEqual(p.in.Layout(), p.out)
 }
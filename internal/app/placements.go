@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+	"github.com/dsrosen6/hyprlaptop/internal/state"
+)
+
+// Workspace placements are snapshotted per monitor identity so that when a monitor is
+// disabled or unplugged, its workspaces and windows can be moved back when it returns.
+// Snapshots of monitors that aren't currently enabled are frozen: while a monitor is
+// away, no other monitor can claim its workspaces, even though Hyprland has moved them.
+
+func (a *App) loadPlacements() {
+	if a.placements != nil {
+		return
+	}
+
+	p, err := state.LoadPlacements()
+	if err != nil {
+		slog.Warn("loading workspace placements; starting fresh", "error", err)
+		p = state.Placements{}
+	}
+
+	a.placements = p
+}
+
+// snapshotPlacements records the workspaces and windows currently on each of the given
+// monitors. enabled is the set of monitor identities currently enabled; workspaces saved
+// for any other monitor are left out.
+func (a *App) snapshotPlacements(monitors []hypr.Monitor, enabled map[string]bool) error {
+	if len(monitors) == 0 {
+		return nil
+	}
+	a.loadPlacements()
+
+	workspaces, err := a.Hctl.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("listing workspaces: %w", err)
+	}
+
+	clients, err := a.Hctl.ListClients()
+	if err != nil {
+		return fmt.Errorf("listing clients: %w", err)
+	}
+
+	frozen := map[int64]bool{}
+	for id, p := range a.placements {
+		if enabled[id] {
+			continue
+		}
+		for _, ws := range p.Workspaces {
+			frozen[ws.ID] = true
+		}
+	}
+
+	now := time.Now()
+	for _, m := range monitors {
+		p := state.Placement{SavedAt: now}
+		onMonitor := map[int64]bool{}
+		for _, ws := range workspaces {
+			// special workspaces (negative IDs) follow the focused monitor on their own
+			if ws.Monitor != m.Name || ws.ID < 0 || frozen[ws.ID] {
+				continue
+			}
+			onMonitor[ws.ID] = true
+			p.Workspaces = append(p.Workspaces, state.PlacedWorkspace{ID: ws.ID, Name: ws.Name})
+		}
+
+		for _, c := range clients {
+			if onMonitor[c.Workspace.ID] {
+				p.Clients = append(p.Clients, state.PlacedClient{
+					Address:     c.Address,
+					Class:       c.Class,
+					WorkspaceID: c.Workspace.ID,
+				})
+			}
+		}
+
+		a.placements[m.Identity()] = p
+		slog.Debug("workspace placement saved", "monitor", m.Name, "identity", m.Identity(),
+			"workspaces", len(p.Workspaces), "clients", len(p.Clients))
+	}
+
+	return nil
+}
+
+// restorePlacements moves the workspaces and windows last saved for each monitor back
+// onto it.
+func (a *App) restorePlacements(monitors []hypr.Monitor) error {
+	if len(monitors) == 0 {
+		return nil
+	}
+	a.loadPlacements()
+
+	workspaces, err := a.Hctl.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("listing workspaces: %w", err)
+	}
+
+	clients, err := a.Hctl.ListClients()
+	if err != nil {
+		return fmt.Errorf("listing clients: %w", err)
+	}
+
+	wsMonitors := make(map[int64]string, len(workspaces))
+	for _, ws := range workspaces {
+		wsMonitors[ws.ID] = ws.Monitor
+	}
+
+	clientWorkspaces := make(map[string]int64, len(clients))
+	for _, c := range clients {
+		clientWorkspaces[c.Address] = c.Workspace.ID
+	}
+
+	for _, m := range monitors {
+		p, ok := a.placements[m.Identity()]
+		if !ok {
+			continue
+		}
+
+		moved := 0
+		for _, c := range p.Clients {
+			cur, ok := clientWorkspaces[c.Address]
+			if !ok || cur == c.WorkspaceID {
+				continue
+			}
+
+			if err := a.Hctl.MoveClientToWorkspace(c.Address, c.WorkspaceID); err != nil {
+				slog.Warn("moving window back to workspace", "address", c.Address, "workspace", c.WorkspaceID, "error", err)
+				continue
+			}
+			// the workspace is created if it no longer exists, somewhere other than this monitor
+			if _, ok := wsMonitors[c.WorkspaceID]; !ok {
+				wsMonitors[c.WorkspaceID] = ""
+			}
+		}
+
+		for _, ws := range p.Workspaces {
+			cur, ok := wsMonitors[ws.ID]
+			if !ok || cur == m.Name {
+				continue
+			}
+
+			if err := a.Hctl.MoveWorkspaceToMonitor(ws.ID, m.Name); err != nil {
+				slog.Warn("moving workspace back to monitor", "workspace", ws.ID, "monitor", m.Name, "error", err)
+				continue
+			}
+			moved++
+		}
+
+		slog.Info("workspace placement restored", "monitor", m.Name, "workspaces_moved", moved)
+	}
+
+	return nil
+}
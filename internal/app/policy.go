@@ -0,0 +1,54 @@
+package app
+
+import (
+	"slices"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+)
+
+// defaultPolicies reproduce hyprlaptop's original behavior, and are checked after any
+// policies in the config.
+var defaultPolicies = []config.Policy{
+	{Status: config.StatusWithExternalLidOpen, EnableLaptop: true, EnableExternals: true},
+	{Status: config.StatusWithExternalLidClosed, EnableExternals: true},
+	{Status: config.StatusOnlyLaptopLidOpen, EnableLaptop: true},
+	{Status: config.StatusOnlyLaptopLidClosed, EnableLaptop: true},
+	// if the lid state can't be determined, leave everything on rather than guessing
+	{Status: config.StatusUnknown, EnableLaptop: true, EnableExternals: true},
+}
+
+// matchPolicy returns the first policy matching the status and connected displays.
+func (a *App) matchPolicy(o *getOutputResult, status config.Status) (config.Policy, bool) {
+	for _, p := range slices.Concat(a.Cfg.Policies, defaultPolicies) {
+		if policyMatches(p, o, status) {
+			return p, true
+		}
+	}
+
+	return config.Policy{}, false
+}
+
+func policyMatches(p config.Policy, o *getOutputResult, status config.Status) bool {
+	if p.Status != "" && p.Status != status {
+		return false
+	}
+
+	n := o.externalCount()
+	if n < p.MinExternals || (p.MaxExternals > 0 && n > p.MaxExternals) {
+		return false
+	}
+
+	for _, name := range p.Present {
+		if _, ok := o.displays[name]; !ok {
+			return false
+		}
+	}
+
+	for _, name := range p.Absent {
+		if _, ok := o.displays[name]; ok {
+			return false
+		}
+	}
+
+	return true
+}
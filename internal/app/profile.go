@@ -0,0 +1,82 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+)
+
+// profileFits reports whether every display in the profile is connected.
+func profileFits(p config.Profile, o *getOutputResult) bool {
+	if len(p.Displays) == 0 {
+		return false
+	}
+
+	for name := range p.Displays {
+		if _, ok := o.displays[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fittingProfiles returns the names of the profiles that fit the connected displays.
+func (a *App) fittingProfiles(o *getOutputResult) []string {
+	var names []string
+	for name, p := range a.Cfg.Profiles {
+		if profileFits(p, o) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// SetProfile applies a profile as a manual override, which lasts until the connected
+// displays change or another mode is set.
+func (a *App) SetProfile(name string) error {
+	if _, ok := a.Cfg.Profiles[name]; !ok {
+		return fmt.Errorf("profile '%s' not found in config", name)
+	}
+
+	current, err := a.Hctl.ListMonitors()
+	if err != nil {
+		return fmt.Errorf("listing current displays: %w", err)
+	}
+
+	if !profileFits(a.Cfg.Profiles[name], &getOutputResult{displays: current}) {
+		return fmt.Errorf("profile '%s' doesn't fit the connected displays", name)
+	}
+
+	a.override = &modeOverride{
+		mode:      modeProfile,
+		profile:   name,
+		connected: connectedIdentities(current),
+	}
+	slog.Info("profile override set", "profile", name)
+
+	return a.Run("PROFILE " + name)
+}
+
+// profilePayloads creates payloads that enable the displays in the profile with their
+// configured layout and disable every other connected display.
+func (a *App) profilePayloads(o *getOutputResult, p config.Profile) []displayPayload {
+	var payloads []displayPayload
+	for _, m := range o.displays {
+		dp := displayPayload{in: m, out: m.Layout()}
+		if c, ok := p.Displays[m.Name]; ok {
+			dp.out = c.Layout()
+			dp.enable = true
+			dp.fromConfig = true
+		}
+
+		dp.update = displayUpdateNeeded(&dp)
+		payloads = append(payloads, dp)
+	}
+
+	return payloads
+}
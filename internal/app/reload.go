@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// configError is why the config on disk was last rejected, if it was.
+type configError struct {
+	err  error
+	time time.Time
+}
+
+// reloadConfig reads the config again and swaps it in if it is valid. If it isn't, the
+// last good config is kept and the user is told why, through the logs, a notification
+// and "hyprlaptop status".
+func (a *App) reloadConfig() error {
+	cfg, err := a.Cfg.Reload(5)
+	if err != nil {
+		a.rejectConfig(err)
+		return err
+	}
+
+	a.Cfg = cfg
+	if a.cfgErr != nil {
+		slog.Info("config is valid again")
+		a.cfgErr = nil
+	}
+
+	return nil
+}
+
+// checkConfig validates the config hyprlaptop started with. There is no earlier config
+// to fall back to, so it is used anyway, but the problems are reported the same way as
+// for a rejected reload.
+func (a *App) checkConfig() {
+	if err := a.Cfg.Validate(); err != nil {
+		a.cfgErr = &configError{err: err, time: time.Now()}
+		slog.Warn("config is invalid", "path", a.Cfg.Path(), "error", err)
+		go notifyConfigError("hyprlaptop config is invalid", err.Error())
+	}
+}
+
+// rejectConfig records why the config was rejected. Saving the same broken config again,
+// or an editor writing it in several steps, isn't reported again unless the problem changed.
+func (a *App) rejectConfig(err error) {
+	if a.cfgErr != nil && a.cfgErr.err.Error() == err.Error() {
+		slog.Debug("config still invalid; not notifying again", "path", a.Cfg.Path(), "error", err)
+		return
+	}
+
+	a.cfgErr = &configError{err: err, time: time.Now()}
+	slog.Error("config rejected; keeping the last good config", "path", a.Cfg.Path(), "error", err)
+	go notifyConfigError("hyprlaptop config not reloaded", fmt.Sprintf("%s\n\nKeeping the last good config.", err))
+}
+
+func notifyConfigError(summary, body string) {
+	if _, err := notify(summary, body); err != nil {
+		slog.Warn("notifying about config error", "error", err)
+	}
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+// Kinds of change made by save-displays.
+const (
+	ChangeAdd    = "add"
+	ChangeUpdate = "update"
+	ChangeRemove = "remove"
+)
+
+type (
+	// SaveDisplaysOptions are the options for the save-displays command.
+	SaveDisplaysOptions struct {
+		// Laptop is the name of the laptop display; if empty, the first eDP display is used.
+		Laptop string `json:"laptop,omitempty"`
+		// Profile saves the displays into the named profile instead of the laptop and
+		// external displays.
+		Profile string `json:"profile,omitempty"`
+		// Replace replaces every saved display instead of only updating connected ones.
+		Replace bool `json:"replace,omitempty"`
+		// Exclude lists displays that are left out of the save.
+		Exclude []string `json:"exclude,omitempty"`
+		// DryRun only works out the changes, without writing them to the config.
+		DryRun bool `json:"dry_run,omitempty"`
+	}
+
+	// SaveDisplaysResult is the data returned by the save-displays command.
+	SaveDisplaysResult struct {
+		Laptop    string          `json:"laptop,omitempty"`
+		Externals []string        `json:"externals"`
+		Profile   string          `json:"profile,omitempty"`
+		Changes   []DisplayChange `json:"changes"`
+		// Kept lists the saved displays a replace left in place because they come from
+		// an included file, which hyprlaptop doesn't write to.
+		Kept    []string `json:"kept,omitempty"`
+		Written bool     `json:"written"`
+	}
+
+	// DisplayChange is a display added to, updated in or removed from the config.
+	DisplayChange struct {
+		Name   string           `json:"name"`
+		Kind   string           `json:"kind"`
+		Laptop bool             `json:"laptop,omitempty"`
+		Before *hypr.Monitor    `json:"before,omitempty"`
+		After  *hypr.Monitor    `json:"after,omitempty"`
+		Fields []hypr.FieldDiff `json:"fields,omitempty"`
+	}
+)
+
+// SaveCurrentDisplays saves the current arrangement of displays into the config. By
+// default it merges: only the connected displays are updated, and saved displays that
+// aren't connected are kept.
+func (a *App) SaveCurrentDisplays(opts SaveDisplaysOptions) (SaveDisplaysResult, error) {
+	displays, err := a.Hctl.ListMonitors()
+	if err != nil {
+		return SaveDisplaysResult{}, fmt.Errorf("listing displays via hyprctl: %w", err)
+	}
+
+	live := hypr.MonitorMap{}
+	for _, m := range displays {
+		// disabled displays don't report a usable layout
+		if !m.Disabled && !slices.Contains(opts.Exclude, m.Name) {
+			live[m.Name] = m.Layout()
+		}
+	}
+
+	if opts.Profile != "" {
+		return a.saveProfile(opts, live)
+	}
+
+	laptop := opts.Laptop
+	if laptop == "" {
+		laptop = a.defaultLaptopDisplay(displays)
+	}
+
+	if laptop == "" {
+		return SaveDisplaysResult{}, errors.New("no laptop display found; pass its name with -laptop")
+	}
+
+	if slices.Contains(opts.Exclude, laptop) {
+		return SaveDisplaysResult{}, fmt.Errorf("the laptop display '%s' can't be excluded", laptop)
+	}
+
+	lm, ok := displays[laptop]
+	if !ok {
+		return SaveDisplaysResult{}, fmt.Errorf("display '%s' not found", laptop)
+	}
+
+	res := SaveDisplaysResult{Laptop: laptop}
+	newLaptop, ok := live[laptop]
+	delete(live, laptop)
+	if !ok {
+		// a disabled laptop display (e.g. with the lid closed) keeps its saved layout
+		if lm.Name != a.Cfg.LaptopDisplay.Name {
+			return SaveDisplaysResult{}, fmt.Errorf("display '%s' is disabled; enable it before saving", lm.Name)
+		}
+		newLaptop = a.Cfg.LaptopDisplay
+	}
+
+	if a.Cfg.LaptopDisplay.Name == "" {
+		res.Changes = append(res.Changes, DisplayChange{Name: laptop, Kind: ChangeAdd, Laptop: true, After: &newLaptop})
+	} else if c := diffDisplay(a.Cfg.LaptopDisplay, newLaptop); c != nil {
+		c.Laptop = true
+		res.Changes = append(res.Changes, *c)
+	}
+
+	externals := mergeDisplays(a.Cfg.ExternalDisplays, live, opts.Replace)
+	res.Kept = keepIncluded(a.Cfg.ExternalDisplays, externals, a.Cfg.MainFileKeys("external_displays"))
+	res.Changes = append(res.Changes, diffDisplays(a.Cfg.ExternalDisplays, externals)...)
+	res.Externals = slices.Sorted(maps.Keys(externals))
+
+	if opts.DryRun || len(res.Changes) == 0 {
+		return res, nil
+	}
+
+	a.Cfg.LaptopDisplay = newLaptop
+	a.Cfg.ExternalDisplays = externals
+	if err := a.Cfg.Write(); err != nil {
+		return SaveDisplaysResult{}, fmt.Errorf("writing config: %w", err)
+	}
+
+	res.Written = true
+	slog.Info("displays saved to config", "laptop", laptop, "changes", len(res.Changes))
+	return res, nil
+}
+
+// saveProfile saves the live displays into a profile, creating it if needed.
+func (a *App) saveProfile(opts SaveDisplaysOptions, live hypr.MonitorMap) (SaveDisplaysResult, error) {
+	existing := a.Cfg.Profiles[opts.Profile]
+	displays := mergeDisplays(existing.Displays, live, opts.Replace)
+	kept := keepIncluded(existing.Displays, displays, a.Cfg.MainFileKeys("profiles", opts.Profile, "displays"))
+
+	res := SaveDisplaysResult{
+		Profile:   opts.Profile,
+		Externals: slices.Sorted(maps.Keys(displays)),
+		Changes:   diffDisplays(existing.Displays, displays),
+		Kept:      kept,
+	}
+
+	if opts.DryRun || len(res.Changes) == 0 {
+		return res, nil
+	}
+
+	existing.Displays = displays
+	if a.Cfg.Profiles == nil {
+		a.Cfg.Profiles = map[string]config.Profile{}
+	}
+	a.Cfg.Profiles[opts.Profile] = existing
+	if err := a.Cfg.Write(); err != nil {
+		return SaveDisplaysResult{}, fmt.Errorf("writing config: %w", err)
+	}
+
+	res.Written = true
+	slog.Info("displays saved to profile", "profile", opts.Profile, "changes", len(res.Changes))
+	return res, nil
+}
+
+// defaultLaptopDisplay returns the configured laptop display if it is connected, or else
+// the first eDP display.
+func (a *App) defaultLaptopDisplay(displays hypr.MonitorMap) string {
+	if name := a.Cfg.LaptopDisplay.Name; name != "" {
+		if _, ok := displays[name]; ok {
+			return name
+		}
+	}
+
+	return findLaptopDisplay(displays)
+}
+
+// findLaptopDisplay returns the name of the first eDP display, or an empty string.
+func findLaptopDisplay(displays hypr.MonitorMap) string {
+	for _, name := range slices.Sorted(maps.Keys(displays)) {
+		if strings.Contains(name, "eDP") {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// mergeDisplays returns the saved displays updated with the live ones. If replace is
+// set, only the live displays are kept.
+func mergeDisplays(saved, live hypr.MonitorMap, replace bool) hypr.MonitorMap {
+	merged := hypr.MonitorMap{}
+	if !replace {
+		maps.Copy(merged, saved)
+	}
+	maps.Copy(merged, live)
+
+	return merged
+}
+
+// keepIncluded puts back the saved displays a replace dropped from merged that the main
+// config file doesn't define, since removing them would only last until the next read.
+// It returns their names, sorted.
+func keepIncluded(saved, merged hypr.MonitorMap, mainKeys map[string]bool) []string {
+	var kept []string
+	for _, name := range slices.Sorted(maps.Keys(saved)) {
+		if _, ok := merged[name]; !ok && !mainKeys[name] {
+			merged[name] = saved[name]
+			kept = append(kept, name)
+		}
+	}
+
+	return kept
+}
+
+// diffDisplays returns the changes between two sets of saved displays, sorted by name.
+func diffDisplays(before, after hypr.MonitorMap) []DisplayChange {
+	names := map[string]bool{}
+	for n := range before {
+		names[n] = true
+	}
+	for n := range after {
+		names[n] = true
+	}
+
+	var changes []DisplayChange
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		b, inBefore := before[name]
+		m, inAfter := after[name]
+		switch {
+		case !inBefore:
+			changes = append(changes, DisplayChange{Name: name, Kind: ChangeAdd, After: &m})
+		case !inAfter:
+			changes = append(changes, DisplayChange{Name: name, Kind: ChangeRemove, Before: &b})
+		default:
+			if c := diffDisplay(b, m); c != nil {
+				changes = append(changes, *c)
+			}
+		}
+	}
+
+	return changes
+}
+
+// diffDisplay returns the change between a saved display and its new layout, or nil if
+// they are the same.
+func diffDisplay(before, after hypr.Monitor) *DisplayChange {
+	fields := before.Diff(after)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &DisplayChange{Name: after.Name, Kind: ChangeUpdate, Before: &before, After: &after, Fields: fields}
+}
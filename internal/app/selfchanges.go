@@ -0,0 +1,70 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/listener"
+)
+
+// selfChangeWindow is how long after hyprlaptop changes a display that matching
+// Hyprland events are treated as caused by that change.
+const selfChangeWindow = 2 * time.Second
+
+// changeTracker records the display changes hyprlaptop made itself, so that the
+// monitor added/removed events they cause don't trigger another run.
+type changeTracker struct {
+	mu      sync.Mutex
+	changes map[string]time.Time
+}
+
+func newChangeTracker() *changeTracker {
+	return &changeTracker{changes: map[string]time.Time{}}
+}
+
+// record notes that hyprlaptop is about to enable, disable or update the display.
+func (t *changeTracker) record(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changes[name] = time.Now()
+}
+
+// selfInduced reports whether a display add/remove event matches a change hyprlaptop
+// made within the last selfChangeWindow.
+func (t *changeTracker) selfInduced(ev listener.Event) bool {
+	if ev.Type != listener.DisplayAddEvent && ev.Type != listener.DisplayRemoveEvent {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at, ok := t.changes[monitorNameFromEvent(ev.Details)]
+	return ok && time.Since(at) <= selfChangeWindow
+}
+
+// recent reports whether hyprlaptop changed any display within the last selfChangeWindow.
+func (t *changeTracker) recent() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, at := range t.changes {
+		if time.Since(at) <= selfChangeWindow {
+			return true
+		}
+	}
+
+	return false
+}
+
+// monitorNameFromEvent gets the monitor name from the details of a monitoraddedv2 or
+// monitorremovedv2 event, which are in the form "ID,NAME,DESCRIPTION".
+func monitorNameFromEvent(details string) string {
+	parts := strings.SplitN(details, ",", 3)
+	if len(parts) < 2 {
+		return details
+	}
+
+	return parts[1]
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"time"
+)
+
+// StatusResult is what "hyprlaptop status" reports.
+type StatusResult struct {
+	// Listener is true when the status came from a running listener; the rest of the
+	// fields after ConfigErrorAt are only set then.
+	Listener bool `json:"listener"`
+
+	ConfigPath    string    `json:"config_path"`
+	ConfigSources []string  `json:"config_sources,omitempty"`
+	ConfigHost    string    `json:"config_host,omitempty"`
+	ConfigError   string    `json:"config_error,omitempty"`
+	ConfigErrorAt time.Time `json:"config_error_at,omitzero"`
+
+	PID       int       `json:"pid,omitempty"`
+	StartedAt time.Time `json:"started_at,omitzero"`
+	Status    string    `json:"status,omitempty"`
+	LidState  string    `json:"lid_state,omitempty"`
+	Mode      string    `json:"mode,omitempty"`
+	Profile   string    `json:"profile,omitempty"`
+	Enabled   []string  `json:"enabled,omitempty"`
+}
+
+// Status reports the state of hyprlaptop and its config. When the listener isn't running,
+// the config is validated on the spot.
+func (a *App) Status() StatusResult {
+	host, _ := a.Cfg.Host()
+	res := StatusResult{
+		Listener:      a.daemon != nil,
+		ConfigPath:    a.Cfg.Path(),
+		ConfigSources: a.Cfg.Sources(),
+		ConfigHost:    host,
+	}
+
+	if a.daemon == nil {
+		if err := a.Cfg.Validate(); err != nil {
+			res.ConfigError = err.Error()
+		}
+		return res
+	}
+
+	if a.cfgErr != nil {
+		res.ConfigError, res.ConfigErrorAt = a.cfgErr.err.Error(), a.cfgErr.time
+	}
+
+	res.PID = a.daemon.PID
+	res.StartedAt = a.daemon.StartedAt
+	res.Status = a.daemon.Status
+	res.LidState = a.daemon.LidState
+	res.Mode = string(a.currentMode())
+	if a.override != nil {
+		res.Profile = a.override.profile
+	}
+	res.Enabled = a.daemon.Enabled
+
+	return res
+}
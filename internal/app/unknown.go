@@ -0,0 +1,355 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/dsrosen6/hyprlaptop/internal/config"
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+// resolution is one of the modes a monitor reports in availableModes.
+type resolution struct {
+	width   int64
+	height  int64
+	refresh float64
+}
+
+const (
+	// baseDPI is the DPI at which a display gets a scale of 1.
+	baseDPI = 96.0
+	// scaleStep is the increment auto scales are rounded to.
+	scaleStep = 0.25
+)
+
+// unknownChoice is what the user chose to do with an unknown display.
+type unknownChoice struct {
+	action config.UnknownDisplayAction
+	opts   config.UnknownDisplayOptions
+}
+
+// unknownDisplayAction returns what to do with a display that isn't in the config.
+// Presentation mode always mirrors; otherwise a choice the user made for the display wins
+// over the config's policy.
+func (a *App) unknownDisplayAction(m hypr.Monitor, ov *modeOverride) (config.UnknownDisplayAction, config.UnknownDisplayOptions) {
+	cfg := a.Cfg.UnknownDisplays
+	if ov != nil && ov.mode == modePresent {
+		return config.UnknownDisplayMirror, cfg.Mirror
+	}
+
+	if c, ok := a.unknownChoices[m.Identity()]; ok {
+		return c.action, c.opts
+	}
+
+	switch cfg.Action {
+	case config.UnknownDisplayExtend:
+		return cfg.Action, cfg.Extend
+	case config.UnknownDisplayMirror:
+		return cfg.Action, cfg.Mirror
+	case "":
+		return config.UnknownDisplayIgnore, config.UnknownDisplayOptions{}
+	default:
+		return cfg.Action, config.UnknownDisplayOptions{}
+	}
+}
+
+// applyUnknownDisplayPolicy updates the payloads of every connected display that isn't in
+// the config according to the unknown display policy. A manual mode other than present
+// takes precedence over the policy, so the displays are left as the mode set them.
+func (a *App) applyUnknownDisplayPolicy(o *getOutputResult, ov *modeOverride, payloads []displayPayload) {
+	a.forgetUnplugged(o)
+	if ov != nil && ov.mode != modePresent {
+		return
+	}
+
+	// extended displays are placed next to the laptop panel, clear of the enabled known
+	// displays and of each other
+	l := &layout{}
+	for _, p := range payloads {
+		// mirroring displays take no space of their own
+		if !p.enable || !a.isKnownDisplay(p.in) || p.out.MirrorOf != "" {
+			continue
+		}
+
+		l.add(p.out)
+		if a.isLaptopDisplay(p.in) {
+			anchor := monitorRect(p.out)
+			l.anchor = &anchor
+		}
+	}
+
+	for i := range payloads {
+		p := &payloads[i]
+		if a.isKnownDisplay(p.in) {
+			continue
+		}
+
+		action, opts := a.unknownDisplayAction(p.in, ov)
+		switch action {
+		case config.UnknownDisplayMirror:
+			a.mirrorLaptopDisplay(o, payloads, p, opts)
+		case config.UnknownDisplayExtend:
+			extendToDisplay(p, opts, l)
+		case config.UnknownDisplayDisable:
+			if p.enable {
+				slog.Info("disabling unknown display", "name", p.in.Name)
+				p.enable = false
+			}
+		case config.UnknownDisplayAsk:
+			a.askAboutUnknownDisplay(p.in)
+		}
+
+		p.update = displayUpdateNeeded(p)
+	}
+}
+
+// forgetUnplugged drops what was asked and chosen about unknown displays that are no
+// longer connected, so plugging one in again asks again.
+func (a *App) forgetUnplugged(o *getOutputResult) {
+	connected := map[string]bool{}
+	for _, m := range o.displays {
+		connected[m.Identity()] = true
+	}
+
+	for id := range a.asked {
+		if !connected[id] {
+			delete(a.asked, id)
+		}
+	}
+
+	for id := range a.unknownChoices {
+		if !connected[id] {
+			delete(a.unknownChoices, id)
+		}
+	}
+}
+
+// isKnownDisplay reports whether the display is the laptop display or is in the config,
+// either as an external display or in a profile.
+func (a *App) isKnownDisplay(m hypr.Monitor) bool {
+	if _, ok := a.getDisplayFromConfig(m); ok {
+		return true
+	}
+
+	for _, p := range a.Cfg.Profiles {
+		if _, ok := p.Displays[m.Name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mirrorLaptopDisplay makes an enabled display mirror the laptop display, at the largest
+// resolution both of them support. The laptop display keeps its own resolution. Nothing
+// is mirrored if the laptop display isn't enabled.
+func (a *App) mirrorLaptopDisplay(o *getOutputResult, payloads []displayPayload, p *displayPayload, opts config.UnknownDisplayOptions) {
+	laptop, ok := o.displays[o.laptopName]
+	if !ok || !p.enable {
+		return
+	}
+
+	for _, lp := range payloads {
+		if a.isLaptopDisplay(lp.in) && !lp.enable {
+			return
+		}
+	}
+
+	p.out.MirrorOf = laptop.Name
+	if res, ok := commonResolution(laptop, p.in); ok {
+		p.out.Width = res.width
+		p.out.Height = res.height
+		p.out.RefreshRate = res.refresh
+	}
+
+	p.out.Scale = opts.Scale
+	if p.out.Scale == 0 {
+		p.out.Scale = autoScale(p.in)
+	}
+
+	slog.Info("mirroring laptop display on unknown display", "name", p.in.Name,
+		"width", p.out.Width, "height", p.out.Height)
+}
+
+// extendToDisplay enables a display at its preferred mode, placed to the configured side
+// of the laptop panel, and adds it to the layout.
+func extendToDisplay(p *displayPayload, opts config.UnknownDisplayOptions, l *layout) {
+	if !p.enable {
+		return
+	}
+
+	if res, ok := preferredResolution(p.in); ok {
+		p.out.Width, p.out.Height, p.out.RefreshRate = res.width, res.height, res.refresh
+	}
+
+	p.out.Scale = opts.Scale
+	if p.out.Scale == 0 {
+		p.out.Scale = autoScale(p.in)
+	}
+
+	p.out.X, p.out.Y = l.place(p.out, opts.Position)
+	l.add(p.out)
+	slog.Info("extending to unknown display", "name", p.in.Name, "x", p.out.X, "y", p.out.Y, "scale", p.out.Scale)
+}
+
+// rect is a monitor's area in layout coordinates.
+type rect struct {
+	x, y, w, h int64
+}
+
+func monitorRect(m hypr.Monitor) rect {
+	w, h := logicalSize(m)
+	return rect{x: m.X, y: m.Y, w: w, h: h}
+}
+
+func (r rect) overlaps(o rect) bool {
+	return r.x < o.x+o.w && o.x < r.x+r.w && r.y < o.y+o.h && o.y < r.y+r.h
+}
+
+// layout is the enabled displays placed so far, and the display new ones are placed
+// next to: the laptop panel if it's enabled, or else all of them together.
+type layout struct {
+	anchor *rect
+	placed []rect
+}
+
+func (l *layout) add(m hypr.Monitor) {
+	l.placed = append(l.placed, monitorRect(m))
+}
+
+// bounds returns the bounding box of the placed displays.
+func (l *layout) bounds() rect {
+	b := l.placed[0]
+	for _, r := range l.placed[1:] {
+		minX, minY := min(b.x, r.x), min(b.y, r.y)
+		maxX, maxY := max(b.x+b.w, r.x+r.w), max(b.y+b.h, r.y+r.h)
+		b = rect{x: minX, y: minY, w: maxX - minX, h: maxY - minY}
+	}
+
+	return b
+}
+
+// place returns the position for a monitor on the given side of the anchor: "right" (the
+// default), "left", "above" or "below". If a placed display is already there, the monitor
+// goes further out on that side, past it.
+func (l *layout) place(m hypr.Monitor, position string) (int64, int64) {
+	if len(l.placed) == 0 {
+		return 0, 0
+	}
+
+	a := l.bounds()
+	if l.anchor != nil {
+		a = *l.anchor
+	}
+
+	w, h := logicalSize(m)
+	r := rect{w: w, h: h}
+	switch position {
+	case "left":
+		r.x, r.y = a.x-w, a.y
+	case "above":
+		r.x, r.y = a.x, a.y-h
+	case "below":
+		r.x, r.y = a.x, a.y+a.h
+	default:
+		r.x, r.y = a.x+a.w, a.y
+	}
+
+	for moved := true; moved; {
+		moved = false
+		for _, o := range l.placed {
+			if !r.overlaps(o) {
+				continue
+			}
+
+			switch position {
+			case "left":
+				r.x = o.x - w
+			case "above":
+				r.y = o.y - h
+			case "below":
+				r.y = o.y + o.h
+			default:
+				r.x = o.x + o.w
+			}
+			moved = true
+		}
+	}
+
+	return r.x, r.y
+}
+
+// autoScale picks a scale from the monitor's DPI, rounded to the nearest quarter and
+// never below 1. Monitors that don't report a physical size get a scale of 1.
+func autoScale(m hypr.Monitor) float64 {
+	if m.PhysicalWidth <= 0 {
+		return 1
+	}
+
+	width := m.Width
+	if res, ok := preferredResolution(m); ok {
+		width = res.width
+	}
+
+	dpi := float64(width) / (float64(m.PhysicalWidth) / 25.4)
+	scale := math.Round(dpi/baseDPI/scaleStep) * scaleStep
+	return max(scale, 1)
+}
+
+// logicalSize returns the size of the monitor in layout coordinates, i.e. after scaling.
+func logicalSize(m hypr.Monitor) (int64, int64) {
+	scale := m.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	return int64(math.Round(float64(m.Width) / scale)), int64(math.Round(float64(m.Height) / scale))
+}
+
+// preferredResolution returns the monitor's preferred mode, which Hyprland lists first.
+func preferredResolution(m hypr.Monitor) (resolution, bool) {
+	if len(m.AvailableModes) == 0 {
+		return resolution{}, false
+	}
+
+	return parseResolution(m.AvailableModes[0])
+}
+
+// commonResolution returns the largest resolution both monitors support, at the best
+// refresh rate the mirroring monitor supports for it.
+func commonResolution(source, mirror hypr.Monitor) (resolution, bool) {
+	sourceSizes := map[[2]int64]bool{}
+	for _, s := range source.AvailableModes {
+		if r, ok := parseResolution(s); ok {
+			sourceSizes[[2]int64{r.width, r.height}] = true
+		}
+	}
+
+	var best resolution
+	found := false
+	for _, s := range mirror.AvailableModes {
+		r, ok := parseResolution(s)
+		if !ok || !sourceSizes[[2]int64{r.width, r.height}] {
+			continue
+		}
+
+		area, bestArea := r.width*r.height, best.width*best.height
+		if !found || area > bestArea || (area == bestArea && r.refresh > best.refresh) {
+			best = r
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// parseResolution parses a mode in the form hyprctl reports them, e.g. "1920x1080@60.00Hz".
+func parseResolution(s string) (resolution, bool) {
+	var r resolution
+	if _, err := fmt.Sscanf(s, "%dx%d@%fHz", &r.width, &r.height, &r.refresh); err != nil {
+		return resolution{}, false
+	}
+
+	return r, true
+}
@@ -8,85 +8,340 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/dsrosen6/hyprlaptop/internal/fileutil"
 	"github.com/dsrosen6/hyprlaptop/internal/hypr"
 )
 
 const (
 	cfgDirName  = "hypr"
-	cfgFileName = "hyprlaptop.json"
+	cfgBaseName = "hyprlaptop"
 )
 
+// ErrInvalid is returned for a config that can't be parsed or fails validation.
+var ErrInvalid = errors.New("invalid config")
+
 type Config struct {
-	path             string
+	path   string
+	format Format
+	// loaded is the config as last read or written, used to find what changed on write.
+	loaded any
+	// sources are the files the config was merged from; see Sources.
+	sources []string
+	// host is the host section merged over the rest of the config, if any.
+	host hostMatch
+
+	// Include lists more config files (or globs) to merge in, relative to this file.
+	Include          []string                `json:"include,omitempty"`
 	LaptopDisplay    hypr.Monitor            `json:"laptop_display"`
 	ExternalDisplays map[string]hypr.Monitor `json:"external_displays"`
+	Policies         []Policy                `json:"policies,omitempty"`
+	Profiles         map[string]Profile      `json:"profiles,omitempty"`
+	UnknownDisplays  UnknownDisplays         `json:"unknown_displays,omitzero"`
+	Learn            Learn                   `json:"learn,omitzero"`
+	// Hosts are per-machine sections, keyed by hostname or DMI product name. The one
+	// matching this machine is merged over the rest of the config.
+	Hosts map[string]map[string]any `json:"hosts,omitempty"`
+}
+
+// Learn configures learn mode, in which the listener saves the arrangement of displays
+// that aren't in the config once the user has rearranged them.
+type Learn struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// StableSeconds is how long a new arrangement must stay unchanged before it is saved.
+	StableSeconds int `json:"stable_seconds,omitempty"`
+}
+
+// UnknownDisplayAction is what hyprlaptop does with a display that isn't in the config.
+type UnknownDisplayAction string
+
+const (
+	// UnknownDisplayIgnore leaves unknown displays as Hyprland set them up.
+	UnknownDisplayIgnore UnknownDisplayAction = "ignore"
+	// UnknownDisplayDisable disables unknown displays.
+	UnknownDisplayDisable UnknownDisplayAction = "disable"
+	// UnknownDisplayExtend enables unknown displays at their preferred mode, placed to
+	// one side of the other displays.
+	UnknownDisplayExtend UnknownDisplayAction = "extend"
+	// UnknownDisplayMirror mirrors the laptop display onto unknown displays, e.g. projectors.
+	UnknownDisplayMirror UnknownDisplayAction = "mirror"
+	// UnknownDisplayAsk notifies the user and leaves the display alone until they decide.
+	UnknownDisplayAsk UnknownDisplayAction = "ask"
+)
+
+// UnknownDisplays is the policy for displays that aren't in the config.
+type UnknownDisplays struct {
+	Action UnknownDisplayAction  `json:"action,omitempty"`
+	Extend UnknownDisplayOptions `json:"extend,omitzero"`
+	Mirror UnknownDisplayOptions `json:"mirror,omitzero"`
+	// SaveChoice saves the result of an action picked from the "ask" notification to
+	// the config's external displays.
+	SaveChoice bool `json:"save_choice,omitempty"`
+}
+
+// UnknownDisplayOptions are the options used when extending onto or mirroring to an
+// unknown display.
+type UnknownDisplayOptions struct {
+	// Position is the side of the laptop panel an extended display is placed on:
+	// "right" (the default), "left", "above" or "below". It is ignored when mirroring.
+	Position string `json:"position,omitempty"`
+	// Scale is a fixed scale for the display. If unset, it is picked from the display's DPI.
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// Profile is a named arrangement of displays that can be applied on demand. When it is
+// applied, connected displays that aren't in the profile are disabled.
+type Profile struct {
+	Displays map[string]hypr.Monitor `json:"displays"`
+}
+
+// Status is the state of the lid and the connected displays, as worked out by the app,
+// which policies are matched against.
+type Status string
+
+const (
+	StatusUnknown               Status = "UNKNOWN"
+	StatusOnlyLaptopLidClosed   Status = "ONLY_LAPTOP_LID_CLOSED"
+	StatusOnlyLaptopLidOpen     Status = "ONLY_LAPTOP_LID_OPEN"
+	StatusWithExternalLidClosed Status = "WITH_EXTERNAL_LID_CLOSED"
+	StatusWithExternalLidOpen   Status = "WITH_EXTERNAL_LID_OPEN"
+)
+
+// Statuses are every status a policy can match.
+var Statuses = []Status{
+	StatusUnknown,
+	StatusOnlyLaptopLidClosed,
+	StatusOnlyLaptopLidOpen,
+	StatusWithExternalLidClosed,
+	StatusWithExternalLidOpen,
+}
+
+// Policy decides which displays are enabled for a given status. User policies are
+// checked in order before the built-in defaults, and the first match wins.
+type Policy struct {
+	// Status is the status this policy applies to, e.g. "WITH_EXTERNAL_LID_OPEN".
+	// An empty status matches any status.
+	Status Status `json:"status,omitempty"`
+	// MinExternals and MaxExternals limit the number of connected external displays.
+	// A MaxExternals of 0 means no upper limit.
+	MinExternals int `json:"min_externals,omitempty"`
+	MaxExternals int `json:"max_externals,omitempty"`
+	// Present and Absent list display names that must or must not be connected.
+	Present []string `json:"present,omitempty"`
+	Absent  []string `json:"absent,omitempty"`
+
+	EnableLaptop    bool `json:"enable_laptop"`
+	EnableExternals bool `json:"enable_externals"`
 }
 
 func defaultCfg(path string) *Config {
 	return &Config{
 		path:             path,
+		format:           formatFromPath(path),
 		LaptopDisplay:    hypr.Monitor{},
 		ExternalDisplays: map[string]hypr.Monitor{},
 	}
 }
 
 func InitConfig(path string) (*Config, error) {
-	uc, err := os.UserConfigDir()
+	path, err := ResolvePath(path)
 	if err != nil {
-		return nil, fmt.Errorf("getting user config directory path: %w", err)
+		return nil, err
 	}
-	defPath := filepath.Join(uc, cfgDirName, cfgFileName)
 
-	if path == "" {
-		path = defPath
+	return readConfig(path, true)
+}
+
+// ResolvePath returns the config file to use: path if it is set, or else the default
+// config in the user's config directory.
+func ResolvePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
 	}
 
-	return readConfig(path, true)
+	uc, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user config directory path: %w", err)
+	}
+
+	return defaultPath(filepath.Join(uc, cfgDirName)), nil
+}
+
+// Read reads the config at path without creating a default one if it doesn't exist.
+func Read(path string) (*Config, error) {
+	return readConfig(path, false)
 }
 
-func (c *Config) Validate() error {
-	if c.LaptopDisplay.Name == "" {
-		return errors.New("laptop display name not set")
+// defaultPath returns the first hyprlaptop config in dir, in any format, or the JSON
+// path if there isn't one.
+func defaultPath(dir string) string {
+	for _, ext := range configExtensions {
+		p := filepath.Join(dir, cfgBaseName+ext)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
 	}
 
-	return nil
+	return filepath.Join(dir, cfgBaseName+configExtensions[0])
 }
 
 func (c *Config) Path() string {
 	return c.path
 }
 
-func (c *Config) Reload(maxRetries int) error {
+// Sources returns the files the config was merged from, in order.
+func (c *Config) Sources() []string {
+	return c.sources
+}
+
+// Format returns the format of the config file.
+func (c *Config) Format() Format {
+	return c.format
+}
+
+// Reload reads the config again from its path and validates it. The current config is
+// left untouched; the caller swaps in the returned one, so nothing ever sees a config
+// that is half updated or invalid.
+func (c *Config) Reload(maxRetries int) (*Config, error) {
 	u, err := readConfigWithRetry(c.path, maxRetries)
 	if err != nil {
-		return fmt.Errorf("reading config: %w", err)
+		return nil, fmt.Errorf("reading config: %w", err)
 	}
 
-	c.LaptopDisplay = u.LaptopDisplay
-	c.ExternalDisplays = u.ExternalDisplays
-	return nil
+	if err := u.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	return u, nil
 }
 
+// Write saves the config atomically. If the file already exists, only the keys that
+// changed since it was read are edited, so the user's comments and formatting are kept.
+// Changes are always written to the main config file, never to included files.
 func (c *Config) Write() error {
 	dir := filepath.Dir(c.path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return fmt.Errorf("checking and/or creating config directory: %w", err)
 	}
 
-	str, err := json.MarshalIndent(c, "", "  ")
+	current, err := toGeneric(c)
 	if err != nil {
-		return fmt.Errorf("marshaling json: %w", err)
+		return fmt.Errorf("converting config: %w", err)
 	}
 
-	if err := os.WriteFile(c.path, str, 0o644); err != nil {
+	b, perm, err := c.render(current)
+	if err != nil {
+		return err
+	}
+
+	// a symlinked config (e.g. from a dotfiles manager) is written through to its target,
+	// so the link is kept
+	path := c.path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	if err := fileutil.WriteFileAtomic(path, b, perm); err != nil {
 		return fmt.Errorf("writing to file: %w", err)
 	}
 
+	c.loaded = current
 	return nil
 }
 
+// render returns the new contents of the config file and the permissions to write it with.
+func (c *Config) render(current any) ([]byte, os.FileMode, error) {
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(c.path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	src, err := os.ReadFile(c.path)
+	if err != nil || c.loaded == nil {
+		// a new config file
+		b, err := encode(c.format, c)
+		return b, perm, err
+	}
+
+	// TOML files are always rewritten, since the TOML encoder can't keep comments
+	edits := c.routeHostEdits(diffGeneric(nil, c.loaded, current))
+	var edit func(src []byte, edits []jsonEdit) ([]byte, error)
+	switch c.format {
+	case FormatJSON:
+		edit = editJSONC
+	case FormatYAML:
+		edit = editYAML
+	}
+
+	if edit != nil {
+		b, err := edit(src, edits)
+		if err == nil {
+			return b, perm, nil
+		}
+		slog.Warn("couldn't edit config in place; rewriting it", "error", err)
+	}
+
+	// rewrite the file with the edits applied, leaving out anything merged in from
+	// other files
+	own, err := decodeGeneric(c.format, src)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading config file: %w", err)
+	}
+
+	for _, e := range edits {
+		applyGenericEdit(own, e)
+	}
+
+	b, err := encodeGeneric(c.format, own)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return b, perm, nil
+}
+
+// Convert writes the config file to a new file in another format next to the current
+// one, and moves the current file aside to "<name>.bak" so the new one is picked up.
+// Included files are left as they are. It returns the path of the new file.
+func (c *Config) Convert(to Format) (string, error) {
+	if to == c.format {
+		return "", fmt.Errorf("config is already %s", to)
+	}
+
+	path := strings.TrimSuffix(c.path, filepath.Ext(c.path)) + to.ext()
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+
+	src, err := os.ReadFile(c.path)
+	if err != nil {
+		return "", fmt.Errorf("reading config file: %w", err)
+	}
+
+	own, err := decodeGeneric(c.format, src)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := encodeGeneric(to, own)
+	if err != nil {
+		return "", err
+	}
+
+	if err := fileutil.WriteFileAtomic(path, b, 0o644); err != nil {
+		return "", fmt.Errorf("writing converted config: %w", err)
+	}
+
+	if err := os.Rename(c.path, c.path+".bak"); err != nil {
+		return "", fmt.Errorf("moving old config aside: %w", err)
+	}
+
+	c.path, c.format = path, to
+	return path, nil
+}
+
 func readConfig(path string, createDefault bool) (*Config, error) {
 	cfg := &Config{}
 	if _, err := os.Stat(path); err != nil {
@@ -110,11 +365,53 @@ func readConfig(path string, createDefault bool) (*Config, error) {
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
 
-	if err := json.Unmarshal(file, cfg); err != nil {
-		return nil, fmt.Errorf("unmarshaling json: %w", err)
+	f := formatFromPath(path)
+	own, err := decodeGeneric(f, file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	srcs, err := sources(path, own)
+	if err != nil {
+		return nil, fmt.Errorf("%w: resolving included files: %w", ErrInvalid, err)
+	}
+
+	merged := map[string]any{}
+	for _, s := range srcs[:len(srcs)-1] {
+		b, err := os.ReadFile(s)
+		if err != nil {
+			return nil, fmt.Errorf("reading included file: %w", err)
+		}
+
+		g, err := decodeGeneric(formatFromPath(s), b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading %s: %w", ErrInvalid, s, err)
+		}
+
+		// includes are only read from the main file
+		delete(g, "include")
+		mergeGeneric(merged, g)
+	}
+	mergeGeneric(merged, own)
+
+	host, ok := applyHostSection(merged, currentMachine())
+	if ok {
+		slog.Debug("using host section", "section", host.section, "matched_by", host.by)
 	}
 
-	cfg.path = path
+	j, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("merging config files: %w", err)
+	}
+
+	if err := json.Unmarshal(j, cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	cfg.path, cfg.format, cfg.sources, cfg.host = path, f, srcs, host
+	if cfg.loaded, err = toGeneric(cfg); err != nil {
+		return nil, fmt.Errorf("converting config: %w", err)
+	}
 	return cfg, nil
 }
 
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// defaultIndent is the indent used when the config file doesn't have any indented keys.
+const defaultIndent = "  "
+
+// jsonEdit sets or removes the value at a path of object keys.
+type jsonEdit struct {
+	path   []string
+	value  any
+	remove bool
+}
+
+// toGeneric converts a value to the generic form encoding/json decodes objects into,
+// keeping numbers as written.
+func toGeneric(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var g any
+	if err := d.Decode(&g); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// editJSONC applies edits to a JSONC document, changing only the keys edited so comments
+// and formatting elsewhere are kept.
+func editJSONC(src []byte, edits []jsonEdit) ([]byte, error) {
+	for _, e := range edits {
+		root, err := parseJSONC(src)
+		if err != nil {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+
+		if !root.isObject {
+			return nil, errors.New("config is not an object")
+		}
+
+		if src, err = applyEdit(src, root, e); err != nil {
+			return nil, fmt.Errorf("editing %s: %w", strings.Join(e.path, "."), err)
+		}
+	}
+
+	if !json.Valid(stripJSONC(src)) {
+		return nil, errors.New("edited config is not valid json")
+	}
+
+	return src, nil
+}
+
+// diffGeneric returns the edits that turn one generic value into another, descending
+// into objects so only the leaves that changed are edited.
+func diffGeneric(path []string, from, to any) []jsonEdit {
+	fm, fok := from.(map[string]any)
+	tm, tok := to.(map[string]any)
+	if !fok || !tok {
+		if reflect.DeepEqual(from, to) {
+			return nil
+		}
+		return []jsonEdit{{path: path, value: to}}
+	}
+
+	var edits []jsonEdit
+	for _, k := range slices.Sorted(maps.Keys(tm)) {
+		p := append(slices.Clone(path), k)
+		if fv, ok := fm[k]; ok {
+			edits = append(edits, diffGeneric(p, fv, tm[k])...)
+		} else {
+			edits = append(edits, jsonEdit{path: p, value: tm[k]})
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(fm)) {
+		if _, ok := tm[k]; !ok {
+			edits = append(edits, jsonEdit{path: append(slices.Clone(path), k), remove: true})
+		}
+	}
+
+	return edits
+}
+
+func applyEdit(src []byte, root *jsoncValue, e jsonEdit) ([]byte, error) {
+	obj := root
+	for i, key := range e.path {
+		idx, ok := obj.member(key)
+		if !ok {
+			if e.remove {
+				return src, nil
+			}
+			return insertMember(src, obj, key, nestValue(e.path[i+1:], e.value))
+		}
+
+		m := obj.members[idx]
+		last := i == len(e.path)-1
+		if last && e.remove {
+			return removeMember(src, obj, idx), nil
+		}
+
+		if last || !m.value.isObject {
+			if e.remove {
+				return src, nil
+			}
+			b, err := json.MarshalIndent(nestValue(e.path[i+1:], e.value), lineIndent(src, m.keyStart), indentUnit(src))
+			if err != nil {
+				return nil, err
+			}
+			return splice(src, m.value.start, m.value.end, b), nil
+		}
+
+		obj = m.value
+	}
+
+	return src, nil
+}
+
+// nestValue wraps a value in objects for each key in path.
+func nestValue(path []string, v any) any {
+	for i := len(path) - 1; i >= 0; i-- {
+		v = map[string]any{path[i]: v}
+	}
+
+	return v
+}
+
+// insertMember adds a key to the end of an object, following the layout of the object's
+// existing members.
+func insertMember(src []byte, obj *jsoncValue, key string, value any) ([]byte, error) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(obj.members) == 0 {
+		indent := lineIndent(src, obj.start) + indentUnit(src)
+		v, err := json.MarshalIndent(value, indent, indentUnit(src))
+		if err != nil {
+			return nil, err
+		}
+		// comments in the empty object are kept, ahead of the new member
+		inner := bytes.TrimRight(src[obj.start+1:obj.end-1], " \t\r\n")
+		text := fmt.Sprintf("%s\n%s%s: %s\n%s", inner, indent, k, v, lineIndent(src, obj.start))
+		return splice(src, obj.start+1, obj.end-1, []byte(text)), nil
+	}
+
+	last := obj.members[len(obj.members)-1]
+	if !bytes.ContainsRune(src[obj.start:last.keyStart], '\n') {
+		// single-line objects stay on one line
+		v, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return splice(src, last.value.end, last.value.end, fmt.Appendf(nil, ", %s: %s", k, v)), nil
+	}
+
+	indent := lineIndent(src, last.keyStart)
+	v, err := json.MarshalIndent(value, indent, indentUnit(src))
+	if err != nil {
+		return nil, err
+	}
+	member := fmt.Sprintf("%s%s: %s", indent, k, v)
+
+	after, trailingComma := last.value.end, false
+	if next := skipSpaceAndComments(src, after); next < len(src) && src[next] == ',' {
+		after, trailingComma = next+1, true
+	}
+
+	if eol, ok := restOfLine(src, after); ok {
+		// the new member goes on the next line, so a comment after the last member stays
+		// with it
+		text := "\n" + member
+		if trailingComma {
+			text += ","
+		}
+		src = splice(src, eol, eol, []byte(text))
+		if !trailingComma {
+			src = splice(src, last.value.end, last.value.end, []byte(","))
+		}
+		return src, nil
+	}
+
+	at := last.value.end
+	if trailingComma {
+		// keep a trailing comma after the new last member
+		at = after - 1
+	}
+
+	return splice(src, at, at, []byte(",\n"+member)), nil
+}
+
+// removeMember removes a key from an object, along with the comma separating it from
+// its neighbours. If the key is on its own line, the line goes too, along with any
+// comment after it and the comment lines directly above it.
+func removeMember(src []byte, obj *jsoncValue, idx int) []byte {
+	m := obj.members[idx]
+	if len(obj.members) == 1 {
+		return splice(src, obj.start+1, obj.end-1, nil)
+	}
+
+	start := m.keyStart
+	ls := lineStart(src, start)
+	ownLine := len(bytes.TrimSpace(src[ls:start])) == 0
+	if ownLine {
+		ls = leadingCommentStart(src, ls)
+	}
+
+	if next := skipSpaceAndComments(src, m.value.end); next < len(src) && src[next] == ',' {
+		end := next + 1
+		if ownLine {
+			start = ls
+			if eol, ok := restOfLine(src, end); ok {
+				end = min(eol+1, len(src))
+			}
+		} else {
+			for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+				end++
+			}
+		}
+		return splice(src, start, end, nil)
+	}
+
+	// the last member: remove the comma after the previous member too
+	end := m.value.end
+	if !ownLine {
+		return splice(src, obj.members[idx-1].value.end, end, nil)
+	}
+
+	if ls > 0 {
+		start = ls - 1
+		if eol, ok := restOfLine(src, end); ok {
+			end = eol
+		}
+	}
+	src = splice(src, start, end, nil)
+
+	comma := skipSpaceAndComments(src, obj.members[idx-1].value.end)
+	if comma < len(src) && src[comma] == ',' {
+		src = splice(src, comma, comma+1, nil)
+	}
+
+	return src
+}
+
+// restOfLine returns the end of the line containing off, if the rest of it after off is
+// only whitespace and a line comment.
+func restOfLine(src []byte, off int) (int, bool) {
+	for off < len(src) && (src[off] == ' ' || src[off] == '\t' || src[off] == '\r') {
+		off++
+	}
+
+	switch {
+	case off == len(src) || src[off] == '\n':
+		return off, true
+	case bytes.HasPrefix(src[off:], []byte("//")):
+		return commentEnd(src, off), true
+	default:
+		return 0, false
+	}
+}
+
+// leadingCommentStart returns the start of the run of comment-only lines directly above
+// the line starting at ls, or ls if there are none.
+func leadingCommentStart(src []byte, ls int) int {
+	for ls > 0 {
+		prev := lineStart(src, ls-1)
+		line := bytes.TrimSpace(src[prev : ls-1])
+		if !bytes.HasPrefix(line, []byte("//")) &&
+			!(bytes.HasPrefix(line, []byte("/*")) && commentEnd(line, 0) == len(line)) {
+			break
+		}
+		ls = prev
+	}
+
+	return ls
+}
+
+func splice(src []byte, start, end int, b []byte) []byte {
+	out := make([]byte, 0, len(src)-(end-start)+len(b))
+	out = append(out, src[:start]...)
+	out = append(out, b...)
+	return append(out, src[end:]...)
+}
+
+func lineStart(src []byte, off int) int {
+	return bytes.LastIndexByte(src[:off], '\n') + 1
+}
+
+// lineIndent returns the leading whitespace of the line containing off.
+func lineIndent(src []byte, off int) string {
+	ls := lineStart(src, off)
+	end := ls
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+
+	return string(src[ls:end])
+}
+
+// indentUnit returns the indent used by the first indented line of the document.
+func indentUnit(src []byte) string {
+	for line := range bytes.Lines(src) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) < len(line) && len(bytes.TrimSpace(trimmed)) > 0 {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+
+	return defaultIndent
+}
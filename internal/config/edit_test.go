@@ -0,0 +1,122 @@
+package config
+
+import "testing"
+
+func TestEditJSONC(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		edits []jsonEdit
+		want  string
+	}{
+		{
+			name:  "replace keeps comments",
+			src:   "{\n  // laptop\n  \"a\": 1, // one\n  \"b\": 2\n}\n",
+			edits: []jsonEdit{{path: []string{"a"}, value: 3}},
+			want:  "{\n  // laptop\n  \"a\": 3, // one\n  \"b\": 2\n}\n",
+		},
+		{
+			name:  "replace nested",
+			src:   "{\n  \"a\": {\n    \"b\": \"x\" // keep\n  }\n}\n",
+			edits: []jsonEdit{{path: []string{"a", "b"}, value: "y"}},
+			want:  "{\n  \"a\": {\n    \"b\": \"y\" // keep\n  }\n}\n",
+		},
+		{
+			name:  "insert after a trailing comment",
+			src:   "{\n  \"a\": 1 // one\n}\n",
+			edits: []jsonEdit{{path: []string{"b"}, value: 2}},
+			want:  "{\n  \"a\": 1, // one\n  \"b\": 2\n}\n",
+		},
+		{
+			name:  "insert keeps a trailing comma",
+			src:   "{\n  \"a\": 1,\n}\n",
+			edits: []jsonEdit{{path: []string{"b"}, value: 2}},
+			want:  "{\n  \"a\": 1,\n  \"b\": 2,\n}\n",
+		},
+		{
+			name:  "insert after a trailing comma and comment",
+			src:   "{\n  \"a\": 1, // one\n}\n",
+			edits: []jsonEdit{{path: []string{"b"}, value: 2}},
+			want:  "{\n  \"a\": 1, // one\n  \"b\": 2,\n}\n",
+		},
+		{
+			name:  "insert into a single-line object",
+			src:   "{\"a\": 1}",
+			edits: []jsonEdit{{path: []string{"b"}, value: 2}},
+			want:  "{\"a\": 1, \"b\": 2}",
+		},
+		{
+			name:  "insert into an object with only a comment",
+			src:   "{ /* nothing yet */ }",
+			edits: []jsonEdit{{path: []string{"b"}, value: 2}},
+			want:  "{ /* nothing yet */\n  \"b\": 2\n}",
+		},
+		{
+			name:  "insert into an empty nested object",
+			src:   "{\n  \"x\": {}\n}\n",
+			edits: []jsonEdit{{path: []string{"x", "y"}, value: 1}},
+			want:  "{\n  \"x\": {\n    \"y\": 1\n  }\n}\n",
+		},
+		{
+			name:  "insert a missing parent",
+			src:   "{\n  \"a\": 1\n}\n",
+			edits: []jsonEdit{{path: []string{"x", "y"}, value: true}},
+			want:  "{\n  \"a\": 1,\n  \"x\": {\n    \"y\": true\n  }\n}\n",
+		},
+		{
+			name:  "remove with its leading comment",
+			src:   "{\n  \"a\": 1,\n  // about b\n  \"b\": 2,\n  \"c\": 3\n}\n",
+			edits: []jsonEdit{{path: []string{"b"}, remove: true}},
+			want:  "{\n  \"a\": 1,\n  \"c\": 3\n}\n",
+		},
+		{
+			name:  "remove with its trailing comment",
+			src:   "{\n  \"a\": 1, // one\n  \"b\": 2\n}\n",
+			edits: []jsonEdit{{path: []string{"a"}, remove: true}},
+			want:  "{\n  \"b\": 2\n}\n",
+		},
+		{
+			name:  "remove the last member and its comments",
+			src:   "{\n  \"a\": 1, // one\n  // about b\n  \"b\": 2 // two\n}\n",
+			edits: []jsonEdit{{path: []string{"b"}, remove: true}},
+			want:  "{\n  \"a\": 1 // one\n}\n",
+		},
+		{
+			name:  "remove the last member with a trailing comma",
+			src:   "{\n  \"a\": 1,\n  \"b\": 2,\n}\n",
+			edits: []jsonEdit{{path: []string{"b"}, remove: true}},
+			want:  "{\n  \"a\": 1,\n}\n",
+		},
+		{
+			name:  "remove from a single-line object",
+			src:   "{\"a\": 1, \"b\": 2, \"c\": 3}",
+			edits: []jsonEdit{{path: []string{"a"}, remove: true}, {path: []string{"c"}, remove: true}},
+			want:  "{\"b\": 2}",
+		},
+		{
+			name:  "remove the only member",
+			src:   "{\n  \"a\": 1\n}\n",
+			edits: []jsonEdit{{path: []string{"a"}, remove: true}},
+			want:  "{}\n",
+		},
+		{
+			name:  "remove a missing key",
+			src:   "{\n  \"a\": 1\n}\n",
+			edits: []jsonEdit{{path: []string{"b", "c"}, remove: true}},
+			want:  "{\n  \"a\": 1\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := editJSONC([]byte(tt.src), tt.edits)
+			if err != nil {
+				t.Fatalf("editJSONC: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("editJSONC:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a config file format.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+	FormatYAML Format = "yaml"
+)
+
+// configExtensions are the config file extensions looked for, in order of preference.
+var configExtensions = []string{".json", ".toml", ".yaml", ".yml"}
+
+// ParseFormat parses a format name, e.g. from the command line.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "toml":
+		return FormatTOML, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unknown config format '%s' (expected json, toml or yaml)", s)
+	}
+}
+
+// formatFromPath picks a format from a file's extension; anything unknown is read as JSON.
+func formatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// ext returns the file extension for the format.
+func (f Format) ext() string {
+	return "." + string(f)
+}
+
+// decodeGeneric reads a config file in the given format into generic values, with
+// numbers from JSON kept as written. TOML and YAML are read with the same keys as JSON.
+func decodeGeneric(f Format, b []byte) (map[string]any, error) {
+	m := map[string]any{}
+	switch f {
+	case FormatTOML:
+		if err := toml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("unmarshaling toml: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("unmarshaling yaml: %w", err)
+		}
+	default:
+		// comments and trailing commas are allowed
+		d := json.NewDecoder(bytes.NewReader(stripJSONC(b)))
+		d.UseNumber()
+		if err := d.Decode(&m); err != nil {
+			return nil, fmt.Errorf("unmarshaling json: %w", err)
+		}
+	}
+
+	if m == nil {
+		// a YAML file with nothing in it
+		m = map[string]any{}
+	}
+
+	return m, nil
+}
+
+// encode writes a whole config in the given format.
+func encode(f Format, c *Config) ([]byte, error) {
+	if f == FormatJSON {
+		b, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("marshaling json: %w", err)
+		}
+		return b, nil
+	}
+
+	g, err := toGeneric(c)
+	if err != nil {
+		return nil, fmt.Errorf("converting config: %w", err)
+	}
+
+	return encodeGeneric(f, g)
+}
+
+// encodeGeneric writes generic values in the given format.
+func encodeGeneric(f Format, g any) ([]byte, error) {
+	g = plainValue(g)
+
+	var buf bytes.Buffer
+	switch f {
+	case FormatJSON:
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("marshaling json: %w", err)
+		}
+		return b, nil
+	case FormatTOML:
+		e := toml.NewEncoder(&buf)
+		e.Indent = ""
+		if err := e.Encode(g); err != nil {
+			return nil, fmt.Errorf("marshaling toml: %w", err)
+		}
+	case FormatYAML:
+		e := yaml.NewEncoder(&buf)
+		e.SetIndent(2)
+		if err := e.Encode(g); err != nil {
+			return nil, fmt.Errorf("marshaling yaml: %w", err)
+		}
+		if err := e.Close(); err != nil {
+			return nil, fmt.Errorf("marshaling yaml: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format '%s'", f)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// plainValue converts a generic value from toGeneric for the TOML and YAML encoders:
+// numbers become ints or floats, and nulls (which TOML can't represent) are dropped.
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			if e != nil {
+				m[k] = plainValue(e)
+			}
+		}
+		return m
+	case []any:
+		s := make([]any, 0, len(v))
+		for _, e := range v {
+			if e != nil {
+				s = append(s, plainValue(e))
+			}
+		}
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// dmiProductNamePath is where the kernel exposes the machine's product name.
+const dmiProductNamePath = "/sys/class/dmi/id/product_name"
+
+// machine identifies the machine hyprlaptop is running on, for picking a host section.
+type machine struct {
+	hostname string
+	product  string
+}
+
+func currentMachine() machine {
+	var m machine
+	if h, err := os.Hostname(); err == nil {
+		m.hostname = h
+	} else {
+		slog.Debug("getting hostname", "error", err)
+	}
+
+	if b, err := os.ReadFile(dmiProductNamePath); err == nil {
+		m.product = strings.TrimSpace(string(b))
+	} else {
+		slog.Debug("reading dmi product name", "error", err)
+	}
+
+	return m
+}
+
+// hostMatch is the host section picked for this machine, and what it was matched by.
+type hostMatch struct {
+	section string
+	by      string
+}
+
+// matchHost picks the host section for a machine: one named after its hostname, or
+// failing that, its DMI product name. Names are compared case-insensitively.
+func matchHost(hosts map[string]any, m machine) (hostMatch, bool) {
+	names := slices.Sorted(maps.Keys(hosts))
+	for _, c := range []struct{ by, value string }{{"hostname", m.hostname}, {"product name", m.product}} {
+		if c.value == "" {
+			continue
+		}
+
+		for _, n := range names {
+			if strings.EqualFold(n, c.value) {
+				return hostMatch{section: n, by: c.by}, true
+			}
+		}
+	}
+
+	return hostMatch{}, false
+}
+
+// Host returns the host section the config was read with, and what it was matched by
+// ("hostname" or "product name"). The section is empty if none matched.
+func (c *Config) Host() (section, by string) {
+	return c.host.section, c.host.by
+}
+
+// applyHostSection merges the section of "hosts" that matches the machine over the rest
+// of the config.
+func applyHostSection(merged map[string]any, m machine) (hostMatch, bool) {
+	hosts, _ := merged["hosts"].(map[string]any)
+	match, ok := matchHost(hosts, m)
+	if !ok {
+		return hostMatch{}, false
+	}
+
+	if section, ok := hosts[match.section].(map[string]any); ok {
+		// a copy is merged so "hosts" itself is left as written
+		mergeGeneric(merged, deepCopy(section).(map[string]any))
+	}
+
+	return match, true
+}
+
+// routeHostEdits moves edits to the host section in use when it sets the top-level key
+// they are under, so a machine's own settings are changed rather than the shared ones.
+// Removals are made in both places, so the value doesn't come back from the other one.
+func (c *Config) routeHostEdits(edits []jsonEdit) []jsonEdit {
+	if c.host.section == "" {
+		return edits
+	}
+
+	section := c.Hosts[c.host.section]
+	var routed []jsonEdit
+	for _, e := range edits {
+		if len(e.path) == 0 || e.path[0] == "hosts" {
+			routed = append(routed, e)
+			continue
+		}
+
+		if _, ok := section[e.path[0]]; !ok {
+			routed = append(routed, e)
+			continue
+		}
+
+		he := e
+		he.path = append([]string{"hosts", c.host.section}, e.path...)
+		routed = append(routed, he)
+		if e.remove {
+			routed = append(routed, e)
+		}
+	}
+
+	return routed
+}
+
+// deepCopy copies generic objects and lists, so merging into the copy leaves the
+// original alone.
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = deepCopy(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DropInDir returns the drop-in directory for a config file, e.g. hyprlaptop.d next
+// to hyprlaptop.json.
+func DropInDir(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".d"
+}
+
+// Sources returns the files that make up the config at path, in the order they are
+// merged: the files in its include list, then the files in its drop-in directory in
+// name order, and finally the file itself, so later files override earlier ones.
+func Sources(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	g, err := decodeGeneric(formatFromPath(path), b)
+	if err != nil {
+		return nil, err
+	}
+
+	return sources(path, g)
+}
+
+func sources(path string, main map[string]any) ([]string, error) {
+	var files []string
+	add := func(f string) {
+		if f != path && !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+
+	includes, _ := main["include"].([]any)
+	for _, inc := range includes {
+		s, ok := inc.(string)
+		if !ok {
+			return nil, fmt.Errorf("include entries must be strings, got %v", inc)
+		}
+
+		pattern := expandPath(s, filepath.Dir(path))
+		if !strings.ContainsAny(pattern, "*?[") {
+			if _, err := os.Stat(pattern); err != nil {
+				return nil, fmt.Errorf("include %s: %w", s, err)
+			}
+			add(pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", s, err)
+		}
+		for _, m := range matches {
+			add(m)
+		}
+	}
+
+	entries, err := os.ReadDir(DropInDir(path))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading drop-in directory: %w", err)
+	}
+
+	// entries are already sorted by name
+	for _, e := range entries {
+		if !e.IsDir() && slices.Contains(configExtensions, strings.ToLower(filepath.Ext(e.Name()))) {
+			add(filepath.Join(DropInDir(path), e.Name()))
+		}
+	}
+
+	return append(files, path), nil
+}
+
+// MainFileKeys returns the keys of the object at path that the main config file sets
+// itself, at the top level or in the host section used, as opposed to ones that only
+// come from included files. Only these can be changed by Write. It returns nil if the
+// file can't be read.
+func (c *Config) MainFileKeys(path ...string) map[string]bool {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil
+	}
+
+	g, err := decodeGeneric(c.format, b)
+	if err != nil {
+		return nil
+	}
+
+	roots := []map[string]any{g}
+	if hosts, ok := g["hosts"].(map[string]any); ok && c.host.section != "" {
+		if section, ok := hosts[c.host.section].(map[string]any); ok {
+			roots = append(roots, section)
+		}
+	}
+
+	keys := map[string]bool{}
+	for _, m := range roots {
+		for _, key := range path {
+			m, _ = m[key].(map[string]any)
+		}
+		for k := range m {
+			keys[k] = true
+		}
+	}
+
+	return keys
+}
+
+// expandPath expands a leading ~ and makes a relative path relative to dir.
+func expandPath(p, dir string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+
+	return filepath.Clean(p)
+}
+
+// mergeGeneric deep-merges src into dst: objects are merged key by key, and anything
+// else in src replaces what's in dst.
+func mergeGeneric(dst, src map[string]any) {
+	for k, v := range src {
+		sm, sok := v.(map[string]any)
+		dm, dok := dst[k].(map[string]any)
+		if sok && dok {
+			mergeGeneric(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// applyGenericEdit applies an edit to a generic object, creating objects along the path
+// as needed.
+func applyGenericEdit(m map[string]any, e jsonEdit) {
+	for i, key := range e.path {
+		if i == len(e.path)-1 {
+			if e.remove {
+				delete(m, key)
+			} else {
+				m[key] = e.value
+			}
+			return
+		}
+
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			if e.remove {
+				return
+			}
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+}
+
+// Dump returns the merged config in its own format, headed by a comment listing the
+// files it was merged from and the host section used.
+func (c *Config) Dump() ([]byte, error) {
+	b, err := encode(c.format, c)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := "#"
+	if c.format == FormatJSON {
+		comment = "//"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s merged from, in order:\n", comment)
+	for _, s := range c.sources {
+		fmt.Fprintf(&sb, "%s   %s\n", comment, s)
+	}
+
+	if c.host.section != "" {
+		fmt.Fprintf(&sb, "%s host section: %s (matched by %s)\n", comment, c.host.section, c.host.by)
+	} else {
+		m := currentMachine()
+		fmt.Fprintf(&sb, "%s host section: none (hostname %q, product name %q)\n", comment, m.hostname, m.product)
+	}
+
+	sb.Write(b)
+	if !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteString("\n")
+	}
+
+	return []byte(sb.String()), nil
+}
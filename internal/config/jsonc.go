@@ -0,0 +1,246 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// stripJSONC turns JSON with comments and trailing commas into plain JSON. Comments are
+// replaced with spaces (keeping newlines) so offsets in errors still line up.
+func stripJSONC(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out = append(out, src[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			end := commentEnd(src, i)
+			for _, b := range src[i:end] {
+				if b == '\n' {
+					out = append(out, '\n')
+				} else {
+					out = append(out, ' ')
+				}
+			}
+			i = end - 1
+		case c == ',':
+			if next := skipSpaceAndComments(src, i+1); next < len(src) && (src[next] == '}' || src[next] == ']') {
+				out = append(out, ' ')
+				continue
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// commentEnd returns the offset just past the comment starting at i.
+func commentEnd(src []byte, i int) int {
+	if src[i+1] == '/' {
+		if j := bytes.IndexByte(src[i:], '\n'); j != -1 {
+			return i + j
+		}
+		return len(src)
+	}
+
+	if j := bytes.Index(src[i+2:], []byte("*/")); j != -1 {
+		return i + 2 + j + 2
+	}
+	return len(src)
+}
+
+// skipSpaceAndComments returns the offset of the next byte at or after i that isn't
+// whitespace or part of a comment.
+func skipSpaceAndComments(src []byte, i int) int {
+	for i < len(src) {
+		switch c := src[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '/' && i+1 < len(src) && (src[i+1] == '/' || src[i+1] == '*'):
+			i = commentEnd(src, i)
+		default:
+			return i
+		}
+	}
+
+	return i
+}
+
+// jsoncValue is a value in a JSONC document, with its offsets in the source.
+type jsoncValue struct {
+	start, end int
+	// members is set for objects, in source order.
+	members  []jsoncMember
+	isObject bool
+}
+
+// jsoncMember is a key and its value in a JSONC object.
+type jsoncMember struct {
+	key      string
+	keyStart int
+	value    *jsoncValue
+}
+
+func (v *jsoncValue) member(key string) (int, bool) {
+	for i, m := range v.members {
+		if m.key == key {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+type jsoncParser struct {
+	src []byte
+	pos int
+}
+
+// parseJSONC parses a JSONC document, keeping the offsets of every value.
+func parseJSONC(src []byte) (*jsoncValue, error) {
+	p := &jsoncParser{src: src}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skip(); p.pos != len(src) {
+		return nil, fmt.Errorf("unexpected data at offset %d", p.pos)
+	}
+
+	return v, nil
+}
+
+func (p *jsoncParser) skip() {
+	p.pos = skipSpaceAndComments(p.src, p.pos)
+}
+
+func (p *jsoncParser) value() (*jsoncValue, error) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return nil, errors.New("unexpected end of input")
+	}
+
+	switch p.src[p.pos] {
+	case '{':
+		return p.object()
+	case '[':
+		return p.array()
+	case '"':
+		start := p.pos
+		if err := p.str(); err != nil {
+			return nil, err
+		}
+		return &jsoncValue{start: start, end: p.pos}, nil
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && !bytes.ContainsRune([]byte(",]}/ \t\r\n"), rune(p.src[p.pos])) {
+			p.pos++
+		}
+		if p.pos == start {
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.src[p.pos], p.pos)
+		}
+		return &jsoncValue{start: start, end: p.pos}, nil
+	}
+}
+
+func (p *jsoncParser) str() error {
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '"':
+			p.pos = i + 1
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unterminated string at offset %d", p.pos)
+}
+
+func (p *jsoncParser) object() (*jsoncValue, error) {
+	v := &jsoncValue{start: p.pos, isObject: true}
+	p.pos++
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			return nil, errors.New("unterminated object")
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			v.end = p.pos
+			return v, nil
+		}
+
+		keyStart := p.pos
+		if p.src[p.pos] != '"' {
+			return nil, fmt.Errorf("expected key at offset %d", p.pos)
+		}
+		if err := p.str(); err != nil {
+			return nil, err
+		}
+
+		var key string
+		if err := json.Unmarshal(p.src[keyStart:p.pos], &key); err != nil {
+			return nil, fmt.Errorf("invalid key at offset %d: %w", keyStart, err)
+		}
+
+		if p.skip(); p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, fmt.Errorf("expected ':' at offset %d", p.pos)
+		}
+		p.pos++
+
+		mv, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		v.members = append(v.members, jsoncMember{key: key, keyStart: keyStart, value: mv})
+
+		if p.skip(); p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+func (p *jsoncParser) array() (*jsoncValue, error) {
+	v := &jsoncValue{start: p.pos}
+	p.pos++
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			return nil, errors.New("unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			v.end = p.pos
+			return v, nil
+		}
+
+		if _, err := p.value(); err != nil {
+			return nil, err
+		}
+
+		if p.skip(); p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+var (
+	unknownDisplayActions = []UnknownDisplayAction{
+		"", UnknownDisplayIgnore, UnknownDisplayDisable, UnknownDisplayExtend, UnknownDisplayMirror, UnknownDisplayAsk,
+	}
+	unknownDisplayPositions = []string{"", "right", "left", "above", "below"}
+)
+
+// Validate checks the config for values hyprlaptop can't work with, returning every
+// problem found.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.LaptopDisplay.Name == "" {
+		errs = append(errs, errors.New("laptop_display.name is not set"))
+	}
+	errs = append(errs, validateMonitor("laptop_display", c.LaptopDisplay)...)
+
+	errs = append(errs, validateDisplays("external_displays", c.ExternalDisplays)...)
+
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		path := fmt.Sprintf("profiles.%s", name)
+		if len(c.Profiles[name].Displays) == 0 {
+			errs = append(errs, fmt.Errorf("%s has no displays", path))
+		}
+		errs = append(errs, validateDisplays(path+".displays", c.Profiles[name].Displays)...)
+	}
+
+	for i, p := range c.Policies {
+		path := fmt.Sprintf("policies[%d]", i)
+		if p.Status != "" && !slices.Contains(Statuses, p.Status) {
+			errs = append(errs, fmt.Errorf("%s.status: unknown status '%s'", path, p.Status))
+		}
+
+		if p.MinExternals < 0 || p.MaxExternals < 0 {
+			errs = append(errs, fmt.Errorf("%s: min_externals and max_externals can't be negative", path))
+		} else if p.MaxExternals > 0 && p.MaxExternals < p.MinExternals {
+			errs = append(errs, fmt.Errorf("%s: max_externals is less than min_externals", path))
+		}
+	}
+
+	u := c.UnknownDisplays
+	if !slices.Contains(unknownDisplayActions, u.Action) {
+		errs = append(errs, fmt.Errorf("unknown_displays.action: unknown action '%s'", u.Action))
+	}
+
+	for _, o := range []struct {
+		name string
+		opts UnknownDisplayOptions
+	}{{"extend", u.Extend}, {"mirror", u.Mirror}} {
+		if !slices.Contains(unknownDisplayPositions, o.opts.Position) {
+			errs = append(errs, fmt.Errorf("unknown_displays.%s.position: unknown position '%s'", o.name, o.opts.Position))
+		}
+		if o.opts.Scale < 0 {
+			errs = append(errs, fmt.Errorf("unknown_displays.%s.scale can't be negative", o.name))
+		}
+	}
+
+	if c.Learn.StableSeconds < 0 {
+		errs = append(errs, errors.New("learn.stable_seconds can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateDisplays checks a map of displays, which must be keyed by display name.
+func validateDisplays(path string, displays map[string]hypr.Monitor) []error {
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(displays)) {
+		m := displays[key]
+		p := fmt.Sprintf("%s.%s", path, key)
+		if m.Name != key {
+			errs = append(errs, fmt.Errorf("%s: name '%s' doesn't match its key", p, m.Name))
+		}
+		errs = append(errs, validateMonitor(p, m)...)
+	}
+
+	return errs
+}
+
+func validateMonitor(path string, m hypr.Monitor) []error {
+	var errs []error
+	if m.Width < 0 || m.Height < 0 {
+		errs = append(errs, fmt.Errorf("%s: width and height can't be negative", path))
+	}
+
+	if m.RefreshRate < 0 {
+		errs = append(errs, fmt.Errorf("%s: refreshRate can't be negative", path))
+	}
+
+	if m.Scale < 0 {
+		errs = append(errs, fmt.Errorf("%s: scale can't be negative", path))
+	}
+
+	return errs
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// editYAML applies edits to a YAML document, changing only the keys edited so comments
+// elsewhere are kept.
+func editYAML(src []byte, edits []jsonEdit) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("config is not a mapping")
+	}
+
+	root := doc.Content[0]
+	for _, e := range edits {
+		if err := applyYAMLEdit(root, e); err != nil {
+			return nil, fmt.Errorf("editing %s: %w", strings.Join(e.path, "."), err)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("marshaling yaml: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("marshaling yaml: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func applyYAMLEdit(m *yaml.Node, e jsonEdit) error {
+	for i, key := range e.path {
+		idx := yamlKeyIndex(m, key)
+		last := i == len(e.path)-1
+		if idx == -1 {
+			if e.remove {
+				return nil
+			}
+
+			k := &yaml.Node{}
+			k.SetString(key)
+			v := &yaml.Node{}
+			if err := v.Encode(plainValue(nestValue(e.path[i+1:], e.value))); err != nil {
+				return err
+			}
+			if len(m.Content) == 0 {
+				// an empty mapping is written as {}, which would keep the flow style
+				m.Style = 0
+			}
+			m.Content = append(m.Content, k, v)
+			return nil
+		}
+
+		old := m.Content[idx+1]
+		if last && e.remove {
+			m.Content = slices.Delete(m.Content, idx, idx+2)
+			return nil
+		}
+
+		if last || old.Kind != yaml.MappingNode {
+			if e.remove {
+				return nil
+			}
+
+			v := &yaml.Node{}
+			if err := v.Encode(plainValue(nestValue(e.path[i+1:], e.value))); err != nil {
+				return err
+			}
+			v.HeadComment, v.LineComment, v.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			m.Content[idx+1] = v
+			return nil
+		}
+
+		m = old
+	}
+
+	return nil
+}
+
+// yamlKeyIndex returns the index of a key's node in a mapping node's content, or -1.
+func yamlKeyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
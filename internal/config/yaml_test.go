@@ -0,0 +1,68 @@
+package config
+
+import "testing"
+
+func TestEditYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		edits []jsonEdit
+		want  string
+	}{
+		{
+			name:  "replace keeps comments",
+			src:   "# hyprlaptop\nlaptop_display:\n  name: eDP-1 # built in\n  scale: 1\n",
+			edits: []jsonEdit{{path: []string{"laptop_display", "scale"}, value: 1.5}},
+			want:  "# hyprlaptop\nlaptop_display:\n  name: eDP-1 # built in\n  scale: 1.5\n",
+		},
+		{
+			name:  "replace keeps the value's comment",
+			src:   "a: 1 # one\n",
+			edits: []jsonEdit{{path: []string{"a"}, value: 2}},
+			want:  "a: 2 # one\n",
+		},
+		{
+			name:  "insert",
+			src:   "a: 1 # one\n",
+			edits: []jsonEdit{{path: []string{"b"}, value: 2}},
+			want:  "a: 1 # one\nb: 2\n",
+		},
+		{
+			name:  "insert a missing parent",
+			src:   "a: 1\n",
+			edits: []jsonEdit{{path: []string{"x", "enabled"}, value: true}},
+			want:  "a: 1\nx:\n  enabled: true\n",
+		},
+		{
+			name:  "fill an empty mapping",
+			src:   "laptop_display:\n  name: eDP-1\nexternal_displays: {}\n",
+			edits: []jsonEdit{{path: []string{"external_displays", "DP-1"}, value: map[string]any{"name": "DP-1", "width": 2560}}},
+			want:  "laptop_display:\n  name: eDP-1\nexternal_displays:\n  DP-1:\n    name: DP-1\n    width: 2560\n",
+		},
+		{
+			name:  "remove with its comments",
+			src:   "a: 1\n# about b\nb: 2 # two\nc: 3\n",
+			edits: []jsonEdit{{path: []string{"b"}, remove: true}},
+			want:  "a: 1\nc: 3\n",
+		},
+		{
+			name:  "remove a missing key",
+			src:   "a: 1\n",
+			edits: []jsonEdit{{path: []string{"x", "y"}, remove: true}},
+			want:  "a: 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := editYAML([]byte(tt.src), tt.edits)
+			if err != nil {
+				t.Fatalf("editYAML: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("editYAML:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
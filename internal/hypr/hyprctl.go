@@ -17,6 +17,24 @@ const (
 
 var ErrUnknownRequest = errors.New(unknownReqOutput)
 
+// CommandError is a hyprctl command that failed, or hyprctl not being found.
+type CommandError struct {
+	Args []string
+	Err  error
+}
+
+func (e *CommandError) Error() string {
+	if len(e.Args) == 0 {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("hyprctl %s: %v", strings.Join(e.Args, " "), e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
 type HyprctlClient struct {
 	binaryPath string
 }
@@ -24,7 +42,7 @@ type HyprctlClient struct {
 func NewHyprctlClient() (*HyprctlClient, error) {
 	bp, err := exec.LookPath(binaryName)
 	if err != nil {
-		return nil, fmt.Errorf("finding full hyprctl binary path: %w", err)
+		return nil, fmt.Errorf("finding full hyprctl binary path: %w", &CommandError{Err: err})
 	}
 
 	return &HyprctlClient{binaryPath: bp}, nil
@@ -51,16 +69,20 @@ func (h *HyprctlClient) RunCommand(args []string) ([]byte, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("running command: %w", err)
+		return nil, &CommandError{Args: args, Err: fmt.Errorf("running command: %w", err)}
 	}
 
 	out := stdout.Bytes()
 	errStr := strings.TrimSpace(stderr.String())
 	if errStr != "" {
-		return nil, errors.New(errStr)
+		return nil, &CommandError{Args: args, Err: errors.New(errStr)}
+	}
+
+	if err := checkForErr(string(out)); err != nil {
+		return nil, &CommandError{Args: args, Err: err}
 	}
 
-	return out, checkForErr(string(out))
+	return out, nil
 }
 
 func checkForErr(out string) error {
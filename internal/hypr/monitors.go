@@ -13,14 +13,27 @@ type (
 		X           int64   `json:"x,omitempty"`
 		Y           int64   `json:"y,omitempty"`
 		Scale       float64 `json:"scale,omitempty"`
+		// MirrorOf is the name of the monitor this one mirrors. Hyprland reports "none"
+		// for monitors that aren't mirroring.
+		MirrorOf string `json:"mirrorOf,omitempty"`
+
+		// Runtime state reported by hyprctl; never applied or saved to config.
+		Description    string   `json:"description,omitempty"`
+		AvailableModes []string `json:"availableModes,omitempty"`
+		// PhysicalWidth and PhysicalHeight are the monitor's size in millimeters.
+		PhysicalWidth  int64 `json:"physicalWidth,omitempty"`
+		PhysicalHeight int64 `json:"physicalHeight,omitempty"`
+		Disabled       bool  `json:"disabled,omitempty"`
+		DpmsStatus     bool  `json:"dpmsStatus,omitempty"`
 	}
 
 	MonitorMap map[string]Monitor
 )
 
+// ListMonitors lists every monitor Hyprland knows about, including disabled ones.
 func (h *HyprctlClient) ListMonitors() (MonitorMap, error) {
 	var monitors []Monitor
-	if err := h.RunCommandWithUnmarshal([]string{"monitors"}, &monitors); err != nil {
+	if err := h.RunCommandWithUnmarshal([]string{"monitors", "all"}, &monitors); err != nil {
 		return nil, err
 	}
 
@@ -50,10 +63,88 @@ func (h *HyprctlClient) DisableMonitor(m Monitor) error {
 	return nil
 }
 
+// Layout returns a copy of the monitor with only the fields hyprlaptop applies
+// via hyprctl, stripping runtime state such as disabled or DPMS status.
+func (m Monitor) Layout() Monitor {
+	return Monitor{
+		Name:        m.Name,
+		Width:       m.Width,
+		Height:      m.Height,
+		RefreshRate: m.RefreshRate,
+		X:           m.X,
+		Y:           m.Y,
+		Scale:       m.Scale,
+		MirrorOf:    m.mirrorSource(),
+	}
+}
+
+// mirrorSource returns the monitor being mirrored, or an empty string if none.
+func (m Monitor) mirrorSource() string {
+	if m.MirrorOf == "none" {
+		return ""
+	}
+
+	return m.MirrorOf
+}
+
+// Identity returns a key identifying the physical monitor, which stays the same if it is
+// plugged into a different port. It falls back to the name if there is no description.
+func (m Monitor) Identity() string {
+	if m.Description != "" {
+		return m.Description
+	}
+
+	return m.Name
+}
+
 func monitorToConfigString(m Monitor) string {
 	res := fmt.Sprintf("%dx%d", m.Width, m.Height)
 	res = fmt.Sprintf("%s@%f", res, m.RefreshRate)
 	xy := fmt.Sprintf("%dx%d", m.X, m.Y)
 	scale := fmt.Sprintf("%f", m.Scale)
-	return fmt.Sprintf("%s,%s,%s,%s", m.Name, res, xy, scale)
+	s := fmt.Sprintf("%s,%s,%s,%s", m.Name, res, xy, scale)
+	if src := m.mirrorSource(); src != "" {
+		s = fmt.Sprintf("%s,mirror,%s", s, src)
+	}
+
+	return s
+}
+
+// FieldDiff is a difference in one field between two monitor layouts.
+type FieldDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Diff compares the layouts of two monitors and returns the fields that differ, named
+// after their JSON keys.
+func (m Monitor) Diff(other Monitor) []FieldDiff {
+	a, b := m.Layout(), other.Layout()
+	fields := []struct {
+		name     string
+		from, to any
+	}{
+		{"name", a.Name, b.Name},
+		{"width", a.Width, b.Width},
+		{"height", a.Height, b.Height},
+		{"refreshRate", a.RefreshRate, b.RefreshRate},
+		{"x", a.X, b.X},
+		{"y", a.Y, b.Y},
+		{"scale", a.Scale, b.Scale},
+		{"mirrorOf", a.MirrorOf, b.MirrorOf},
+	}
+
+	var diffs []FieldDiff
+	for _, f := range fields {
+		if f.from != f.to {
+			diffs = append(diffs, FieldDiff{
+				Field: f.name,
+				From:  fmt.Sprint(f.from),
+				To:    fmt.Sprint(f.to),
+			})
+		}
+	}
+
+	return diffs
 }
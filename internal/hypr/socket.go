@@ -12,6 +12,8 @@ const (
 	runtimeEnv = "XDG_RUNTIME_DIR"
 	sigEnv     = "HYPRLAND_INSTANCE_SIGNATURE"
 	sockName   = ".socket2.sock"
+	// requestSockName is the socket hyprctl sends requests on.
+	requestSockName = ".socket.sock"
 )
 
 var ErrMissingEnvs = errors.New("missing hyprland envs")
@@ -20,14 +22,36 @@ type SocketConn struct {
 	*net.UnixConn
 }
 
+// MissingEnvs returns the names of the environment variables needed to find Hyprland's
+// sockets that aren't set.
+func MissingEnvs() []string {
+	var missing []string
+	for _, e := range []string{runtimeEnv, sigEnv} {
+		if os.Getenv(e) == "" {
+			missing = append(missing, e)
+		}
+	}
+
+	return missing
+}
+
+// SocketPaths returns the paths of the running Hyprland instance's request and event
+// sockets.
+func SocketPaths() (request, event string, err error) {
+	if len(MissingEnvs()) > 0 {
+		return "", "", ErrMissingEnvs
+	}
+
+	dir := filepath.Join(os.Getenv(runtimeEnv), "hypr", os.Getenv(sigEnv))
+	return filepath.Join(dir, requestSockName), filepath.Join(dir, sockName), nil
+}
+
 func NewSocketConn() (*SocketConn, error) {
-	runtime := os.Getenv(runtimeEnv)
-	sig := os.Getenv(sigEnv)
-	if runtime == "" || sig == "" {
-		return nil, ErrMissingEnvs
+	_, sock, err := SocketPaths()
+	if err != nil {
+		return nil, err
 	}
 
-	sock := filepath.Join(runtime, "hypr", sig, sockName)
 	addr := &net.UnixAddr{
 		Name: sock,
 		Net:  "unix",
@@ -0,0 +1,64 @@
+package hypr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type (
+	Workspace struct {
+		ID      int64  `json:"id"`
+		Name    string `json:"name"`
+		Monitor string `json:"monitor"`
+		Windows int64  `json:"windows"`
+	}
+
+	Client struct {
+		Address   string       `json:"address"`
+		Class     string       `json:"class"`
+		Title     string       `json:"title"`
+		Workspace WorkspaceRef `json:"workspace"`
+	}
+
+	WorkspaceRef struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+)
+
+func (h *HyprctlClient) ListWorkspaces() ([]Workspace, error) {
+	var workspaces []Workspace
+	if err := h.RunCommandWithUnmarshal([]string{"workspaces"}, &workspaces); err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+func (h *HyprctlClient) ListClients() ([]Client, error) {
+	var clients []Client
+	if err := h.RunCommandWithUnmarshal([]string{"clients"}, &clients); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (h *HyprctlClient) MoveWorkspaceToMonitor(workspaceID int64, monitor string) error {
+	args := []string{"dispatch", "moveworkspacetomonitor", strconv.FormatInt(workspaceID, 10), monitor}
+	if _, err := h.RunCommand(args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MoveClientToWorkspace moves a window to a workspace without following it.
+func (h *HyprctlClient) MoveClientToWorkspace(address string, workspaceID int64) error {
+	args := []string{"dispatch", "movetoworkspacesilent", fmt.Sprintf("%d,address:%s", workspaceID, address)}
+	if _, err := h.RunCommand(args); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -2,21 +2,73 @@ package listener
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-var CommandSockName = "hyprlaptop.sock"
+const (
+	CommandSockName = "hyprlaptop.sock"
+
+	// replyTimeout is how long the command listener waits for the app to handle a request.
+	replyTimeout = 30 * time.Second
+)
+
+// Request is a command sent to the listener by the hyprlaptop CLI.
+type Request struct {
+	Command EventType `json:"command"`
+	Args    []string  `json:"args,omitempty"`
+	// ConfigPath is the absolute path of the config the CLI was pointed at with -c, if
+	// it was. The listener refuses requests for a config it isn't using.
+	ConfigPath string `json:"config_path,omitempty"`
+}
+
+// Response is the listener's reply to a Request. Data holds command-specific output,
+// and Code classifies the error, if any.
+type Response struct {
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Code  string          `json:"code,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// commandEvents are the events that may be requested via the command socket.
+var commandEvents = map[EventType]bool{
+	LidSwitchEvent:      true,
+	IdleWakeEvent:       true,
+	DisplayUnknownEvent: true,
+	RefreshEvent:        true,
+	SaveDisplaysEvent:   true,
+	RestoreLayoutEvent:  true,
+	SetModeEvent:        true,
+	CycleModeEvent:      true,
+	SetProfileEvent:     true,
+	StatusEvent:         true,
+}
+
+// CommandSockPath returns the path of the command socket, in the user's runtime directory.
+func CommandSockPath() string {
+	return filepath.Join(RuntimeDir(), CommandSockName)
+}
+
+// RuntimeDir returns $XDG_RUNTIME_DIR, falling back to the temp directory if it isn't set.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+
+	return os.TempDir()
+}
 
 // commandListener listens for CLI-specific commands besides "listen" and performs
 // actions when required.
 func (l *Listener) commandListener(ctx context.Context, events chan<- Event) error {
-	sock := filepath.Join(os.TempDir(), CommandSockName)
+	sock := CommandSockPath()
 
 	// remove exiting file if it already exists
 	_ = os.Remove(sock)
@@ -51,20 +103,50 @@ func (l *Listener) commandListener(ctx context.Context, events chan<- Event) err
 					}
 				}()
 
-				buf, _ := io.ReadAll(conn)
-				msg := strings.TrimSpace(string(buf))
-
-				switch msg {
-				case string(LidSwitchEvent):
-					events <- Event{Type: LidSwitchEvent}
-				case string(IdleWakeEvent):
-					events <- Event{Type: IdleWakeEvent}
-				case string(DisplayUnknownEvent):
-					events <- Event{Type: DisplayUnknownEvent}
-				default:
-					slog.Warn("command listener: got unknown message", "msg", msg)
+				resp := handleRequest(ctx, conn, events)
+				if err := json.NewEncoder(conn).Encode(resp); err != nil {
+					slog.Error("command listener: writing response", "error", err)
 				}
 			}()
 		}
 	}
 }
+
+// handleRequest reads a request from the connection, passes it on as an event and waits
+// for the app to reply.
+func handleRequest(ctx context.Context, conn net.Conn, events chan<- Event) Response {
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		slog.Warn("command listener: got invalid request", "error", err)
+		return Response{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+
+	if !commandEvents[req.Command] {
+		slog.Warn("command listener: got unknown command", "command", req.Command)
+		return Response{Error: fmt.Sprintf("unknown command %q", req.Command)}
+	}
+
+	reply := make(chan Response, 1)
+	ev := Event{
+		Type:       req.Command,
+		Details:    strings.Join(req.Args, " "),
+		Args:       req.Args,
+		ConfigPath: req.ConfigPath,
+		Reply:      reply,
+	}
+
+	select {
+	case events <- ev:
+	case <-ctx.Done():
+		return Response{Error: "listener shutting down"}
+	}
+
+	select {
+	case resp := <-reply:
+		return resp
+	case <-time.After(replyTimeout):
+		return Response{Error: "timed out waiting for listener"}
+	case <-ctx.Done():
+		return Response{Error: "listener shutting down"}
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 
+	"github.com/dsrosen6/hyprlaptop/internal/config"
 	"github.com/fsnotify/fsnotify"
 )
 
@@ -28,11 +30,10 @@ func (l *Listener) listenForConfigChanges(ctx context.Context, events chan<- Eve
 		}
 	}()
 
-	dir := filepath.Dir(l.cfgPath)
-	err = w.Add(dir)
-	if err != nil {
+	if err := w.Add(filepath.Dir(l.cfgPath)); err != nil {
 		return fmt.Errorf("adding config directory to watcher: %w", err)
 	}
+	cw := l.watchConfigSources(w)
 	slog.Debug("config watcher: fsnotify watch list", "list", w.WatchList())
 
 	for {
@@ -45,24 +46,36 @@ func (l *Listener) listenForConfigChanges(ctx context.Context, events chan<- Eve
 				return nil
 			}
 
-			if event.Has(fsnotify.Write) || event.Has(fsnotify.Rename) {
-				h, err := fileHash(l.cfgPath)
-				if err != nil {
-					continue
-				}
+			// atomic saves (including hyprlaptop's own) rename a temp file over the config,
+			// which shows up as a create, and repointing a symlink removes and recreates it
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) &&
+				!event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) {
+				continue
+			}
 
-				if h == lastHash {
-					slog.Debug("config watcher: received identical hash for file update, no changes needed")
-					continue
-				}
+			if !cw.relevant(event.Name) {
+				continue
+			}
 
-				lastHash = h
+			// the include list or a symlink may have changed, so the sources are resolved again
+			cw = l.watchConfigSources(w)
+			h, err := filesHash(cw.files)
+			if err != nil {
+				slog.Debug("config watcher: hashing config files", "error", err)
+				continue
+			}
 
-				slog.Debug("fsnotify: file modified", "file", event.Name)
-				events <- Event{
-					Type:    ConfigUpdatedEvent,
-					Details: l.cfgPath,
-				}
+			if h == lastHash {
+				slog.Debug("config watcher: received identical hash for file update, no changes needed")
+				continue
+			}
+
+			lastHash = h
+
+			slog.Debug("fsnotify: file modified", "file", event.Name)
+			events <- Event{
+				Type:    ConfigUpdatedEvent,
+				Details: l.cfgPath,
 			}
 
 		case err, ok := <-w.Errors:
@@ -74,10 +87,122 @@ func (l *Listener) listenForConfigChanges(ctx context.Context, events chan<- Eve
 	}
 }
 
-func fileHash(path string) ([32]byte, error) {
-	data, err := os.ReadFile(path)
+// configWatch is what the config watcher watches: every file the config is merged from,
+// and every symlink on the way to each of them.
+type configWatch struct {
+	// files are the resolved config files, in merge order.
+	files []string
+	// names are the paths whose events matter: each source, every link in its chain and
+	// its target, and dropIns the drop-in directory and its links, whose entries all matter.
+	names   map[string]bool
+	dropIns map[string]bool
+	// dirs are the directories to watch.
+	dirs map[string]bool
+	// unresolved is set when the sources couldn't be resolved, so any event may matter.
+	unresolved bool
+}
+
+func (cw configWatch) relevant(name string) bool {
+	name = filepath.Clean(name)
+	return cw.unresolved || cw.names[name] || cw.dropIns[name] || cw.dropIns[filepath.Dir(name)]
+}
+
+// watchConfigSources resolves the files the config is merged from, following symlinks,
+// and updates the watcher to watch the directory of each link and target. If the sources
+// can't be resolved (e.g. the config is mid-edit), just the main config file is used.
+func (l *Listener) watchConfigSources(w *fsnotify.Watcher) configWatch {
+	cw := configWatch{names: map[string]bool{}, dropIns: map[string]bool{}, dirs: map[string]bool{}}
+	sources, err := config.Sources(l.cfgPath)
 	if err != nil {
-		return [32]byte{}, err
+		slog.Debug("config watcher: resolving config sources", "error", err)
+		sources = []string{l.cfgPath}
+		cw.unresolved = true
+	}
+
+	for _, s := range sources {
+		chain := symlinkChain(s)
+		for _, p := range chain {
+			cw.names[p] = true
+			cw.dirs[filepath.Dir(p)] = true
+		}
+		cw.files = append(cw.files, chain[len(chain)-1])
+	}
+
+	for _, p := range symlinkChain(config.DropInDir(l.cfgPath)) {
+		cw.dropIns[p] = true
+		cw.dirs[filepath.Dir(p)] = true
+		cw.dirs[p] = true
+	}
+
+	watched := w.WatchList()
+	for d := range cw.dirs {
+		if slices.Contains(watched, d) {
+			continue
+		}
+
+		// directories that don't exist yet (e.g. the drop-in directory) are picked up
+		// once their parent gets an event for them
+		if err := w.Add(d); err == nil {
+			slog.Debug("config watcher: watching directory", "dir", d)
+		}
 	}
-	return sha256.Sum256(data), nil
+
+	// stop watching directories a repointed symlink no longer leads through
+	for _, d := range watched {
+		if !cw.dirs[d] {
+			if err := w.Remove(d); err == nil {
+				slog.Debug("config watcher: stopped watching directory", "dir", d)
+			}
+		}
+	}
+
+	return cw
+}
+
+// symlinkChain returns path followed by every link it resolves through, ending with the
+// final target. Symlinked parent directories are resolved for the final target too.
+func symlinkChain(path string) []string {
+	chain := []string{filepath.Clean(path)}
+	// the same limit as the kernel, so a loop can't go on forever
+	for range 40 {
+		p := chain[len(chain)-1]
+		fi, err := os.Lstat(p)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			break
+		}
+
+		target, err := os.Readlink(p)
+		if err != nil {
+			break
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(p), target)
+		}
+		chain = append(chain, filepath.Clean(target))
+	}
+
+	last := chain[len(chain)-1]
+	if resolved, err := filepath.EvalSymlinks(last); err == nil && resolved != last {
+		chain = append(chain, resolved)
+	}
+
+	return chain
+}
+
+// filesHash hashes the names and contents of a list of files together.
+func filesHash(paths []string) ([32]byte, error) {
+	h := sha256.New()
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		h.Write([]byte(p))
+		h.Write(data)
+	}
+
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
 }
@@ -3,6 +3,12 @@ package listener
 type Event struct {
 	Type    EventType
 	Details string
+	Args    []string
+	// ConfigPath is the config a CLI request is for, if it named one.
+	ConfigPath string
+
+	// Reply is set for events sent by the hyprlaptop CLI that expect a response.
+	Reply chan<- Response
 }
 
 // EventType is mostly for logging, but this may change in the future.
@@ -15,4 +21,14 @@ const (
 	DisplayUnknownEvent EventType = "DISLAY_UNKNOWN_EVENT"
 	IdleWakeEvent       EventType = "IDLE_WAKE"
 	LidSwitchEvent      EventType = "LID_SWITCH"
+	RefreshEvent        EventType = "REFRESH"
+	SaveDisplaysEvent   EventType = "SAVE_DISPLAYS"
+	RestoreLayoutEvent  EventType = "RESTORE_LAYOUT"
+	SetModeEvent        EventType = "SET_MODE"
+	CycleModeEvent      EventType = "CYCLE_MODE"
+	SetProfileEvent     EventType = "SET_PROFILE"
+	StatusEvent         EventType = "STATUS"
+	// UnknownDisplayChoiceEvent is sent internally when the user picks an action from
+	// the notification for an unknown display.
+	UnknownDisplayChoiceEvent EventType = "UNKNOWN_DISPLAY_CHOICE"
 )
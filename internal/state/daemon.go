@@ -0,0 +1,68 @@
+package state
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+const (
+	daemonFile = "state.json"
+	bootIDFile = "/proc/sys/kernel/random/boot_id"
+)
+
+// Daemon is what the listener knows about the displays, persisted after every run so
+// it survives restarts.
+type Daemon struct {
+	PID       int       `json:"pid"`
+	BootID    string    `json:"boot_id"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Running is true while the listener is running, and is cleared on a clean shutdown.
+	// If it is still set on startup, the previous listener crashed.
+	Running bool `json:"running"`
+
+	Status   string `json:"status,omitempty"`
+	LidState string `json:"lid_state,omitempty"`
+	// Connected holds the identities of every connected monitor, and Enabled the ones
+	// that were enabled, as of the last run.
+	Connected []string `json:"connected,omitempty"`
+	Enabled   []string `json:"enabled,omitempty"`
+	// Applied is the last set of displays hyprlaptop applied, with disabled ones marked.
+	Applied []hypr.Monitor `json:"applied,omitempty"`
+
+	// Mode is the manual mode override, if any, Profile the profile it applies, and
+	// ModeConnected the identities of the monitors connected when it was set.
+	Mode          string   `json:"mode,omitempty"`
+	Profile       string   `json:"profile,omitempty"`
+	ModeConnected []string `json:"mode_connected,omitempty"`
+}
+
+// LoadDaemon reads the persisted listener state. It returns nil if none was saved.
+func LoadDaemon() (*Daemon, error) {
+	var d *Daemon
+	if err := readJSON(daemonFile, &d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Save atomically writes the listener state to the state directory.
+func (d *Daemon) Save() error {
+	d.UpdatedAt = time.Now()
+	return writeJSON(daemonFile, d)
+}
+
+// CurrentBootID returns the kernel's ID for the current boot, or an empty string if it
+// can't be read.
+func CurrentBootID() string {
+	b, err := os.ReadFile(bootIDFile)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
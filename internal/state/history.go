@@ -0,0 +1,53 @@
+package state
+
+import (
+	"time"
+
+	"github.com/dsrosen6/hyprlaptop/internal/hypr"
+)
+
+const (
+	historyFile = "history.json"
+
+	// MaxHistory is the number of applied layouts kept in the history.
+	MaxHistory = 20
+)
+
+type (
+	// History is the list of layouts hyprlaptop applied, newest first.
+	History []HistoryEntry
+
+	HistoryEntry struct {
+		Time    time.Time `json:"time"`
+		Trigger string    `json:"trigger"`
+		Status  string    `json:"status"`
+		// Monitors are the applied displays, with disabled ones marked.
+		Monitors []hypr.Monitor `json:"monitors"`
+	}
+)
+
+// LoadHistory reads the layout history from the state directory.
+func LoadHistory() (History, error) {
+	var h History
+	if err := readJSON(historyFile, &h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Add returns the history with the entry added at the front, dropping the oldest entries
+// beyond MaxHistory.
+func (h History) Add(e HistoryEntry) History {
+	h = append(History{e}, h...)
+	if len(h) > MaxHistory {
+		h = h[:MaxHistory]
+	}
+
+	return h
+}
+
+// Save atomically writes the history to the state directory.
+func (h History) Save() error {
+	return writeJSON(historyFile, h)
+}
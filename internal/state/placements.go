@@ -0,0 +1,46 @@
+package state
+
+import (
+	"time"
+)
+
+const placementsFile = "placements.json"
+
+type (
+	// Placements maps a monitor's identity to what was last placed on it.
+	Placements map[string]Placement
+
+	// Placement is a snapshot of the workspaces and windows on a monitor.
+	Placement struct {
+		SavedAt    time.Time         `json:"saved_at"`
+		Workspaces []PlacedWorkspace `json:"workspaces"`
+		Clients    []PlacedClient    `json:"clients"`
+	}
+
+	PlacedWorkspace struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+
+	PlacedClient struct {
+		Address     string `json:"address"`
+		Class       string `json:"class,omitempty"`
+		WorkspaceID int64  `json:"workspace_id"`
+	}
+)
+
+// LoadPlacements reads saved placements from the state directory. It returns an empty
+// set if none have been saved.
+func LoadPlacements() (Placements, error) {
+	p := Placements{}
+	if err := readJSON(placementsFile, &p); err != nil || p == nil {
+		return Placements{}, err
+	}
+
+	return p, nil
+}
+
+// Save atomically writes the placements to the state directory.
+func (p Placements) Save() error {
+	return writeJSON(placementsFile, p)
+}
@@ -0,0 +1,72 @@
+// Package state handles hyprlaptop's persisted runtime state, stored under
+// $XDG_STATE_HOME/hyprlaptop.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dsrosen6/hyprlaptop/internal/fileutil"
+)
+
+const dirName = "hyprlaptop"
+
+// Dir returns the hyprlaptop state directory, falling back to ~/.local/state if
+// XDG_STATE_HOME isn't set.
+func Dir() (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("getting user home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(base, dirName), nil
+}
+
+// readJSON reads the named file in the state directory into v. A missing file leaves v
+// untouched and is not an error.
+func readJSON(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading state file: %w", err)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("unmarshaling state file: %w", err)
+	}
+
+	return nil
+}
+
+// writeJSON atomically writes v to the named file in the state directory.
+func writeJSON(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("checking and/or creating state directory: %w", err)
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling json: %w", err)
+	}
+
+	return fileutil.WriteFileAtomic(filepath.Join(dir, name), b, 0o644)
+}